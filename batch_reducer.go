@@ -0,0 +1,38 @@
+package eventstore
+
+import "context"
+
+// BatchReducer can be implemented by a [Reducer]
+// to receive multiple events per call of [Reducer.Reduce],
+// e.g. to bulk upsert read models with fewer round trips.
+// Eventstores which don't support batching call Reduce per event.
+type BatchReducer interface {
+	// BatchSize is the maximum number of events passed to Reduce
+	// 0 doesn't limit the size of a batch
+	BatchSize() int
+	// BatchByTransaction ends a batch after the last event of a transaction
+	// i.e. a batch only contains events of the same [Event.Position]
+	BatchByTransaction() bool
+}
+
+// withBatching returns wrapped which keeps the batching of reducer
+// it's used if a [Reducer] is wrapped before it's passed to an [Eventstore]
+func withBatching(reducer, wrapped Reducer) Reducer {
+	batch, ok := reducer.(BatchReducer)
+	if !ok {
+		return wrapped
+	}
+	return &batchingReducer{Reducer: wrapped, BatchReducer: batch}
+}
+
+var _ ContextReducer = (*batchingReducer)(nil)
+
+type batchingReducer struct {
+	Reducer
+	BatchReducer
+}
+
+// ReduceContext implements [ContextReducer]
+func (r *batchingReducer) ReduceContext(ctx context.Context, events ...Event) error {
+	return Reduce(ctx, r.Reducer, events...)
+}
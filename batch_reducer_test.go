@@ -0,0 +1,42 @@
+package eventstore
+
+import "testing"
+
+type testBatchReducer struct {
+	reduceFunc
+	size int
+}
+
+// BatchSize implements [BatchReducer]
+func (r *testBatchReducer) BatchSize() int {
+	return r.size
+}
+
+// BatchByTransaction implements [BatchReducer]
+func (*testBatchReducer) BatchByTransaction() bool {
+	return true
+}
+
+func Test_withBatching(t *testing.T) {
+	var reduced bool
+	wrapped := reduceFunc(func(...Event) error {
+		reduced = true
+		return nil
+	})
+
+	reducer := withBatching(&testBatchReducer{size: 10}, wrapped)
+	batching, ok := reducer.(BatchReducer)
+	if !ok {
+		t.Fatal("batching of the reducer must be kept")
+	}
+	if batching.BatchSize() != 10 || !batching.BatchByTransaction() {
+		t.Errorf("unexpected batching: %d, %v", batching.BatchSize(), batching.BatchByTransaction())
+	}
+	if err := reducer.Reduce(); err != nil || !reduced {
+		t.Errorf("wrapped reducer must be called: %v", err)
+	}
+
+	if _, ok = withBatching(wrapped, wrapped).(BatchReducer); ok {
+		t.Error("reducers without batching must not batch")
+	}
+}
@@ -0,0 +1,45 @@
+package eventstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBlobNotFound is returned by [BlobStore.Get] if the key is not stored
+	ErrBlobNotFound = errors.New("blob not found")
+	// ErrBlobHashMismatch is returned if the content of a blob doesn't match its key
+	ErrBlobHashMismatch = errors.New("content of blob does not match its key")
+)
+
+// BlobStore stores large payloads outside of the eventstore.
+// Blobs are content-addressed using [BlobKey],
+// therefore the same key always stores the same data.
+type BlobStore interface {
+	// Put stores data by key, storing an existing key must succeed
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data of key or [ErrBlobNotFound]
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the data of key, deleting a missing key must succeed
+	Delete(ctx context.Context, key string) error
+}
+
+// blobKeyPrefix names the hash function of the key
+const blobKeyPrefix = "sha256-"
+
+// BlobKey returns the content-addressed key of data
+func BlobKey(data []byte) string {
+	hash := sha256.Sum256(data)
+	return blobKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+// VerifyBlob returns [ErrBlobHashMismatch] if data was not stored by key
+func VerifyBlob(key string, data []byte) error {
+	if BlobKey(data) != key {
+		return fmt.Errorf("%w: %q", ErrBlobHashMismatch, key)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Package blob contains implementations of [eventstore.BlobStore]
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// ErrInvalidKey is returned if a key can't be used as file name
+var ErrInvalidKey = errors.New("invalid blob key")
+
+var _ eventstore.BlobStore = (*FileStore)(nil)
+
+// FileStore stores each blob as file in a directory on the local filesystem
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore stores the blobs in dir, the directory is created if it doesn't exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put implements [eventstore.BlobStore]
+// The file is written atomically, existing blobs are not rewritten.
+func (store *FileStore) Put(_ context.Context, key string, data []byte) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(path); err == nil {
+		return nil
+	}
+
+	file, err := os.CreateTemp(store.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Get implements [eventstore.BlobStore]
+func (store *FileStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", eventstore.ErrBlobNotFound, key)
+	}
+	return data, err
+}
+
+// Delete implements [eventstore.BlobStore]
+func (store *FileStore) Delete(_ context.Context, key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file of the key
+// keys must not escape the directory
+func (store *FileStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(store.dir, key), nil
+}
@@ -0,0 +1,92 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unable to create store: %v", err)
+	}
+
+	data := []byte("payload")
+	key := eventstore.BlobKey(data)
+	for range 2 {
+		if err = store.Put(ctx, key, data); err != nil {
+			t.Fatalf("unable to put blob: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		want    []byte
+		wantErr error
+	}{
+		{
+			name: "stored",
+			key:  key,
+			want: data,
+		},
+		{
+			name:    "not found",
+			key:     eventstore.BlobKey([]byte("other")),
+			wantErr: eventstore.ErrBlobNotFound,
+		},
+		{
+			name:    "escaping key",
+			key:     "../" + key,
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:    "hidden key",
+			key:     ".tmp-123",
+			wantErr: ErrInvalidKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Get(ctx, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error want: %v, got: %v", tt.wantErr, err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("unexpected data want: %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFileStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unable to create store: %v", err)
+	}
+
+	data := []byte("payload")
+	key := eventstore.BlobKey(data)
+	if err = store.Put(ctx, key, data); err != nil {
+		t.Fatalf("unable to put blob: %v", err)
+	}
+
+	// deleting a missing key succeeds
+	for range 2 {
+		if err = store.Delete(ctx, key); err != nil {
+			t.Fatalf("unable to delete blob: %v", err)
+		}
+	}
+	if _, err = store.Get(ctx, key); !errors.Is(err, eventstore.ErrBlobNotFound) {
+		t.Errorf("expected %v after delete, got: %v", eventstore.ErrBlobNotFound, err)
+	}
+	if err = store.Delete(ctx, "../"+key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected %v, got: %v", ErrInvalidKey, err)
+	}
+}
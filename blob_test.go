@@ -0,0 +1,41 @@
+package eventstore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyBlob(t *testing.T) {
+	data := []byte("payload")
+	tests := []struct {
+		name string
+		key  string
+		data []byte
+		want error
+	}{
+		{
+			name: "valid",
+			key:  BlobKey(data),
+			data: data,
+		},
+		{
+			name: "tampered",
+			key:  BlobKey(data),
+			data: []byte("tampered"),
+			want: ErrBlobHashMismatch,
+		},
+		{
+			name: "other hash",
+			key:  "md5-" + BlobKey(data)[len(blobKeyPrefix):],
+			data: data,
+			want: ErrBlobHashMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyBlob(tt.key, tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("unexpected error want: %v, got: %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Package buffer provides a store-and-forward wrapper around an [eventstore.Eventstore].
+//
+// If the eventstore is unavailable, pushes which don't depend on the state of the
+// eventstore are written to a local write-ahead log and replayed in order
+// as soon as the eventstore is ready again.
+package buffer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var (
+	_      eventstore.Eventstore = (*Buffer)(nil)
+	logger                       = slog.Default()
+
+	// ErrClosed is returned if the buffer is used after [Buffer.Close]
+	ErrClosed = errors.New("buffer closed")
+)
+
+// Buffer writes pushes to a local write-ahead log while the wrapped eventstore is unavailable.
+//
+// Only pushes without preconditions are buffered:
+//   - the [eventstore.Expectation] of all aggregates is [eventstore.ExpectAny]
+//   - no aggregate has an [eventstore.AppendCondition]
+//   - no command has [eventstore.UniqueConstraint]s
+//   - all commands are [eventstore.IdempotentCommand]s with an idempotency key
+//   - no command has an [eventstore.DataSubjectCommand.DataSubject]
+//
+// The idempotency key is required because a push which timed out might have been stored,
+// the replay of the buffered push is deduplicated by the key.
+// Payloads of data subjects are not buffered because the write-ahead log is not encrypted
+// and the payload could not be deleted by [eventstore.Forgetter.ForgetSubject].
+// Buffered commands don't receive the sequence, creation date and position of the stored events.
+//
+// Filter and Ready are passed to the wrapped eventstore,
+// buffered commands are not visible until they are replayed.
+type Buffer struct {
+	store eventstore.Eventstore
+	log   *wal
+	codec eventstore.Codec
+
+	replayInterval time.Duration
+	// replayMu ensures that only one replay runs at a time
+	replayMu sync.Mutex
+
+	closed    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New opens the write-ahead log inside dir and starts replaying
+// previously buffered pushes in the background.
+// [Buffer.Close] must be called to stop the replay.
+func New(store eventstore.Eventstore, dir string, opts ...bufferOpt) (*Buffer, error) {
+	log, err := openWAL(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &Buffer{
+		store:          store,
+		log:            log,
+		codec:          eventstore.JSONCodec,
+		replayInterval: time.Second,
+		closed:         make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(buffer)
+	}
+
+	go buffer.run()
+
+	return buffer, nil
+}
+
+type bufferOpt func(*Buffer)
+
+func WithLogger(l *slog.Logger) bufferOpt {
+	return func(*Buffer) {
+		logger = l
+	}
+}
+
+// WithReplayInterval defines how often the readiness of the eventstore is checked
+// while pushes are buffered. Default is 1s.
+func WithReplayInterval(interval time.Duration) bufferOpt {
+	return func(buffer *Buffer) {
+		buffer.replayInterval = interval
+	}
+}
+
+// WithCodec encodes the payloads of buffered commands using codec.
+// It should be the codec of the wrapped eventstore,
+// the payloads are replayed as [eventstore.RawPayloadCommand]s. Default is [eventstore.JSONCodec].
+func WithCodec(codec eventstore.Codec) bufferOpt {
+	return func(buffer *Buffer) {
+		buffer.codec = codec
+	}
+}
+
+// Backlog describes the pushes which are not yet replayed
+type Backlog struct {
+	// Pushes is the amount of buffered calls of [Buffer.Push]
+	Pushes int
+	// Commands is the amount of buffered commands
+	Commands int
+	// Bytes is the size of the buffered pushes on disk
+	Bytes int64
+}
+
+// Backlog returns the current size of the backlog
+func (buffer *Buffer) Backlog() Backlog {
+	return buffer.log.backlog()
+}
+
+// Ready implements [eventstore.Eventstore]
+func (buffer *Buffer) Ready(ctx context.Context) error {
+	return buffer.store.Ready(ctx)
+}
+
+// Filter implements [eventstore.Eventstore]
+func (buffer *Buffer) Filter(ctx context.Context, filter *eventstore.Filter, reducer eventstore.Reducer) error {
+	return buffer.store.Filter(ctx, filter, reducer)
+}
+
+// Push implements [eventstore.Eventstore]
+// The aggregates are buffered if they can be buffered and either the eventstore is unavailable
+// or previous pushes are still buffered
+func (buffer *Buffer) Push(ctx context.Context, aggregates ...eventstore.Aggregate) error {
+	if !bufferable(aggregates) {
+		return buffer.store.Push(ctx, aggregates...)
+	}
+
+	// buffered pushes must be replayed before new pushes are stored
+	if buffer.log.backlog().Pushes == 0 {
+		err := buffer.store.Push(ctx, aggregates...)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if readyErr := buffer.store.Ready(ctx); readyErr == nil {
+			return err
+		}
+		logger.WarnContext(ctx, "eventstore unavailable, push is buffered", "cause", err)
+	}
+
+	record, err := newRecord(ctx, buffer.codec, aggregates)
+	if err != nil {
+		return err
+	}
+	return buffer.log.append(record)
+}
+
+// Flush replays the buffered pushes in order.
+// It stops at the first push which fails because the eventstore is unavailable.
+// Pushes which are rejected by an available eventstore are logged and written
+// to the rejected log inside the directory of the buffer.
+func (buffer *Buffer) Flush(ctx context.Context) error {
+	buffer.replayMu.Lock()
+	defer buffer.replayMu.Unlock()
+
+	for {
+		record, next, err := buffer.log.next()
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return buffer.log.compact()
+		}
+
+		if err = buffer.store.Push(ctx, record.aggregates()...); err != nil {
+			if readyErr := buffer.store.Ready(ctx); readyErr != nil || ctx.Err() != nil {
+				return err
+			}
+			logger.ErrorContext(ctx, "buffered push rejected", "cause", err)
+			if err = buffer.log.reject(record); err != nil {
+				return err
+			}
+		}
+
+		if err = buffer.log.commit(next, record); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops the replay and closes the write-ahead log
+// pushes which are still buffered are replayed after the next call of [New]
+func (buffer *Buffer) Close() error {
+	buffer.closeOnce.Do(func() {
+		close(buffer.closed)
+	})
+	<-buffer.done
+	return buffer.log.close()
+}
+
+func (buffer *Buffer) run() {
+	defer close(buffer.done)
+
+	ticker := time.NewTicker(buffer.replayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-buffer.closed:
+			return
+		case <-ticker.C:
+			buffer.replay()
+		}
+	}
+}
+
+func (buffer *Buffer) replay() {
+	if buffer.log.backlog().Pushes == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), buffer.replayInterval)
+	defer cancel()
+
+	if err := buffer.store.Ready(ctx); err != nil {
+		logger.DebugContext(ctx, "eventstore still unavailable", "cause", err)
+		return
+	}
+	if err := buffer.Flush(ctx); err != nil {
+		logger.WarnContext(ctx, "replay of buffered pushes failed", "cause", err)
+	}
+}
+
+// bufferable checks if the aggregates don't depend on the state of the eventstore
+func bufferable(aggregates []eventstore.Aggregate) bool {
+	for _, aggregate := range aggregates {
+		if !eventstore.ExpectationOf(aggregate).IsAny() {
+			return false
+		}
+		if conditional, ok := aggregate.(eventstore.ConditionalAggregate); ok && conditional.AppendCondition() != nil {
+			return false
+		}
+		for _, command := range aggregate.Commands() {
+			if constraints, ok := command.(eventstore.UniqueConstraintCommand); ok && len(constraints.UniqueConstraints()) > 0 {
+				return false
+			}
+			if idempotent, ok := command.(eventstore.IdempotentCommand); !ok || idempotent.IdempotencyKey() == "" {
+				return false
+			}
+			if subject, ok := command.(eventstore.DataSubjectCommand); ok && subject.DataSubject() != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,473 @@
+package buffer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+var _ eventstore.Eventstore = (*testStore)(nil)
+
+type testStore struct {
+	mu          sync.Mutex
+	unavailable bool
+	rejectErr   error
+	pushed      []eventstore.TextSubjects
+	tenants     []string
+}
+
+func (s *testStore) setUnavailable(unavailable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unavailable = unavailable
+}
+
+// Ready implements [eventstore.Eventstore]
+func (s *testStore) Ready(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.unavailable {
+		return errUnavailable
+	}
+	return nil
+}
+
+// Filter implements [eventstore.Eventstore]
+func (*testStore) Filter(context.Context, *eventstore.Filter, eventstore.Reducer) error {
+	return nil
+}
+
+// Push implements [eventstore.Eventstore]
+func (s *testStore) Push(ctx context.Context, aggregates ...eventstore.Aggregate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.unavailable {
+		return errUnavailable
+	}
+	if s.rejectErr != nil {
+		return s.rejectErr
+	}
+	for _, aggregate := range aggregates {
+		s.tenants = append(s.tenants, eventstore.TenantOf(ctx, aggregate))
+		for _, command := range aggregate.Commands() {
+			// replayed commands must be idempotent
+			if buffered, ok := command.(*bufferedCommand); ok && buffered.IdempotencyKey() == "" {
+				return errors.New("idempotency key missing")
+			}
+			s.pushed = append(s.pushed, command.Action())
+		}
+	}
+	return nil
+}
+
+type testAggregate struct {
+	id       eventstore.TextSubjects
+	sequence *uint32
+	commands []eventstore.Command
+}
+
+// ID implements [eventstore.Aggregate]
+func (a *testAggregate) ID() eventstore.TextSubjects { return a.id }
+
+// Commands implements [eventstore.Aggregate]
+func (a *testAggregate) Commands() []eventstore.Command { return a.commands }
+
+// CurrentSequence implements [eventstore.Aggregate]
+func (a *testAggregate) CurrentSequence() *uint32 { return a.sequence }
+
+type testCommand struct {
+	action         eventstore.TextSubjects
+	payload        any
+	idempotencyKey string
+}
+
+// Action implements [eventstore.Command]
+func (c *testCommand) Action() eventstore.TextSubjects { return c.action }
+
+// Revision implements [eventstore.Command]
+func (*testCommand) Revision() uint16 { return 1 }
+
+// Payload implements [eventstore.Command]
+func (c *testCommand) Payload() any { return c.payload }
+
+// SetSequence implements [eventstore.Command]
+func (*testCommand) SetSequence(uint32) {}
+
+// SetCreationDate implements [eventstore.Command]
+func (*testCommand) SetCreationDate(time.Time) {}
+
+// IdempotencyKey implements [eventstore.IdempotentCommand]
+func (c *testCommand) IdempotencyKey() string { return c.idempotencyKey }
+
+// SetDeduplicated implements [eventstore.IdempotentCommand]
+func (*testCommand) SetDeduplicated() {}
+
+func newTestAggregate(id string, actions ...string) *testAggregate {
+	aggregate := &testAggregate{id: eventstore.TextSubjects{"audit", eventstore.TextSubject(id)}}
+	for _, action := range actions {
+		aggregate.commands = append(aggregate.commands, &testCommand{
+			action:         eventstore.TextSubjects{"audit", eventstore.TextSubject(id), eventstore.TextSubject(action)},
+			payload:        map[string]string{"action": action},
+			idempotencyKey: id + "-" + action,
+		})
+	}
+	return aggregate
+}
+
+func newTestBuffer(t *testing.T, store eventstore.Eventstore, dir string) *Buffer {
+	t.Helper()
+	// the background replay is disabled to keep the tests deterministic
+	buffer, err := New(store, dir, WithReplayInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("unable to open buffer: %v", err)
+	}
+	return buffer
+}
+
+func TestBuffer_Push(t *testing.T) {
+	sequence := uint32(1)
+	tests := []struct {
+		name            string
+		unavailable     bool
+		aggregates      []eventstore.Aggregate
+		expectedErr     error
+		expectedBacklog Backlog
+		expectedPushed  []eventstore.TextSubjects
+	}{
+		{
+			name:       "available",
+			aggregates: []eventstore.Aggregate{newTestAggregate("1", "logged")},
+			expectedPushed: []eventstore.TextSubjects{
+				{"audit", "1", "logged"},
+			},
+		},
+		{
+			name:            "unavailable",
+			unavailable:     true,
+			aggregates:      []eventstore.Aggregate{newTestAggregate("1", "logged", "viewed")},
+			expectedBacklog: Backlog{Pushes: 1, Commands: 2},
+		},
+		{
+			name:        "unavailable with expectation",
+			unavailable: true,
+			aggregates: []eventstore.Aggregate{
+				&testAggregate{
+					id:       eventstore.TextSubjects{"audit", "1"},
+					sequence: &sequence,
+					commands: newTestAggregate("1", "logged").commands,
+				},
+			},
+			expectedErr: errUnavailable,
+		},
+		{
+			name:        "unavailable without idempotency key",
+			unavailable: true,
+			aggregates: []eventstore.Aggregate{
+				&testAggregate{
+					id: eventstore.TextSubjects{"audit", "1"},
+					commands: []eventstore.Command{
+						&testCommand{action: eventstore.TextSubjects{"audit", "1", "logged"}},
+					},
+				},
+			},
+			expectedErr: errUnavailable,
+		},
+		{
+			name:        "unavailable with data subject",
+			unavailable: true,
+			aggregates: []eventstore.Aggregate{
+				&testAggregate{
+					id: eventstore.TextSubjects{"audit", "1"},
+					commands: []eventstore.Command{
+						&testDataSubjectCommand{
+							testCommand: testCommand{
+								action:         eventstore.TextSubjects{"audit", "1", "logged"},
+								payload:        map[string]string{"email": "gigi@giraffe.ch"},
+								idempotencyKey: "1",
+							},
+							subject: "gigi",
+						},
+					},
+				},
+			},
+			expectedErr: errUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &testStore{unavailable: tt.unavailable}
+			buffer := newTestBuffer(t, store, t.TempDir())
+			defer buffer.Close()
+
+			err := buffer.Push(context.Background(), tt.aggregates...)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got: %v", tt.expectedErr, err)
+			}
+
+			backlog := buffer.Backlog()
+			backlog.Bytes = 0
+			if backlog != tt.expectedBacklog {
+				t.Errorf("unexpected backlog want: %+v, got: %+v", tt.expectedBacklog, backlog)
+			}
+			if !reflect.DeepEqual(store.pushed, tt.expectedPushed) {
+				t.Errorf("unexpected pushed commands want: %v, got: %v", tt.expectedPushed, store.pushed)
+			}
+		})
+	}
+}
+
+func TestBuffer_Flush(t *testing.T) {
+	store := &testStore{unavailable: true}
+	buffer := newTestBuffer(t, store, t.TempDir())
+	defer buffer.Close()
+
+	ctx := context.Background()
+	for _, aggregate := range []*testAggregate{
+		newTestAggregate("1", "logged"),
+		newTestAggregate("2", "logged"),
+	} {
+		if err := buffer.Push(ctx, aggregate); err != nil {
+			t.Fatalf("unexpected error on push: %v", err)
+		}
+	}
+
+	if err := buffer.Flush(ctx); !errors.Is(err, errUnavailable) {
+		t.Errorf("expected %v during flush, got: %v", errUnavailable, err)
+	}
+
+	store.setUnavailable(false)
+	// the backlog must be replayed before new pushes are stored
+	if err := buffer.Push(ctx, newTestAggregate("3", "logged")); err != nil {
+		t.Fatalf("unexpected error on push: %v", err)
+	}
+	if len(store.pushed) > 0 {
+		t.Fatalf("push must be buffered while the backlog is not empty, got: %v", store.pushed)
+	}
+
+	if err := buffer.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error on flush: %v", err)
+	}
+
+	want := []eventstore.TextSubjects{
+		{"audit", "1", "logged"},
+		{"audit", "2", "logged"},
+		{"audit", "3", "logged"},
+	}
+	if !reflect.DeepEqual(store.pushed, want) {
+		t.Errorf("unexpected replay want: %v, got: %v", want, store.pushed)
+	}
+	if backlog := buffer.Backlog(); backlog != (Backlog{}) {
+		t.Errorf("backlog must be empty after flush, got: %+v", backlog)
+	}
+}
+
+func TestBuffer_Flush_tenant(t *testing.T) {
+	store := &testStore{unavailable: true}
+	buffer := newTestBuffer(t, store, t.TempDir())
+	defer buffer.Close()
+
+	if err := buffer.Push(eventstore.WithTenant(context.Background(), "tenant"), newTestAggregate("1", "logged")); err != nil {
+		t.Fatalf("unexpected error on push: %v", err)
+	}
+
+	store.setUnavailable(false)
+	// the replay doesn't have the context of the push
+	if err := buffer.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error on flush: %v", err)
+	}
+	if want := []string{"tenant"}; !reflect.DeepEqual(store.tenants, want) {
+		t.Errorf("tenant must be kept during replay want: %v, got: %v", want, store.tenants)
+	}
+}
+
+func TestBuffer_Flush_rejected(t *testing.T) {
+	dir := t.TempDir()
+	store := &testStore{unavailable: true}
+	buffer := newTestBuffer(t, store, dir)
+	defer buffer.Close()
+
+	ctx := context.Background()
+	if err := buffer.Push(ctx, newTestAggregate("1", "logged")); err != nil {
+		t.Fatalf("unexpected error on push: %v", err)
+	}
+
+	store.setUnavailable(false)
+	store.rejectErr = errors.New("invalid")
+	if err := buffer.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error on flush: %v", err)
+	}
+
+	if backlog := buffer.Backlog(); backlog != (Backlog{}) {
+		t.Errorf("backlog must be empty after flush, got: %+v", backlog)
+	}
+	rejected, err := os.ReadFile(filepath.Join(dir, rejectedFile))
+	if err != nil {
+		t.Fatalf("unable to read rejected log: %v", err)
+	}
+	if len(rejected) == 0 {
+		t.Error("rejected push must be written to the rejected log")
+	}
+}
+
+func TestBuffer_reopen(t *testing.T) {
+	dir := t.TempDir()
+	store := &testStore{unavailable: true}
+	ctx := context.Background()
+
+	buffer := newTestBuffer(t, store, dir)
+	for _, aggregate := range []*testAggregate{
+		newTestAggregate("1", "logged"),
+		newTestAggregate("2", "logged"),
+	} {
+		if err := buffer.Push(ctx, aggregate); err != nil {
+			t.Fatalf("unexpected error on push: %v", err)
+		}
+	}
+	store.setUnavailable(false)
+	// replay only the first push
+	record, next, err := buffer.log.next()
+	if err != nil {
+		t.Fatalf("unexpected error on next: %v", err)
+	}
+	if err = buffer.log.commit(next, record); err != nil {
+		t.Fatalf("unexpected error on commit: %v", err)
+	}
+	if err = buffer.Close(); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+	if err = buffer.Push(ctx, newTestAggregate("3", "logged")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v after close, got: %v", ErrClosed, err)
+	}
+
+	buffer = newTestBuffer(t, store, dir)
+	defer buffer.Close()
+
+	if backlog := buffer.Backlog(); backlog.Pushes != 1 || backlog.Commands != 1 {
+		t.Errorf("unexpected backlog after reopen: %+v", backlog)
+	}
+	if err = buffer.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error on flush: %v", err)
+	}
+	want := []eventstore.TextSubjects{{"audit", "2", "logged"}}
+	if !reflect.DeepEqual(store.pushed, want) {
+		t.Errorf("unexpected replay want: %v, got: %v", want, store.pushed)
+	}
+}
+
+func Test_wal_writeOffset(t *testing.T) {
+	log, err := openWAL(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error on open: %v", err)
+	}
+	defer log.close()
+
+	for _, offset := range []int64{42, 0} {
+		if err = log.writeOffset(offset); err != nil {
+			t.Fatalf("unexpected error on write offset: %v", err)
+		}
+		got, err := os.ReadFile(filepath.Join(log.dir, offsetFile))
+		if err != nil {
+			t.Fatalf("unexpected error on read offset: %v", err)
+		}
+		if want := strconv.FormatInt(offset, 10); string(got) != want {
+			t.Errorf("unexpected offset want: %s, got: %s", want, got)
+		}
+		if _, err = os.Stat(filepath.Join(log.dir, offsetFile+".tmp")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("temporary offset file must be replaced: %v", err)
+		}
+	}
+}
+
+type testRawCommand struct {
+	testCommand
+	payload []byte
+	codec   string
+}
+
+// RawPayload implements [eventstore.RawPayloadCommand]
+func (c *testRawCommand) RawPayload() ([]byte, string) { return c.payload, c.codec }
+
+type testDataSubjectCommand struct {
+	testCommand
+	subject string
+}
+
+// DataSubject implements [eventstore.DataSubjectCommand]
+func (c *testDataSubjectCommand) DataSubject() string { return c.subject }
+
+func Test_newCommandRecord_payload(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     eventstore.Command
+		wantPayload []byte
+		wantCodec   string
+	}{
+		{
+			name: "encoded by codec",
+			command: &testCommand{
+				action:         eventstore.TextSubjects{"audit", "1", "logged"},
+				payload:        map[string]string{"action": "logged"},
+				idempotencyKey: "1",
+			},
+			wantPayload: []byte(`{"action":"logged"}`),
+			wantCodec:   "json",
+		},
+		{
+			name: "raw payload",
+			command: &testRawCommand{
+				testCommand: testCommand{
+					action:         eventstore.TextSubjects{"audit", "1", "logged"},
+					idempotencyKey: "1",
+				},
+				payload: []byte{0x0a, 0x06, 'l', 'o', 'g', 'g', 'e', 'd'},
+				codec:   "proto",
+			},
+			wantPayload: []byte{0x0a, 0x06, 'l', 'o', 'g', 'g', 'e', 'd'},
+			wantCodec:   "proto",
+		},
+		{
+			name: "no payload",
+			command: &testCommand{
+				action:         eventstore.TextSubjects{"audit", "1", "logged"},
+				idempotencyKey: "1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := newCommandRecord(context.Background(), eventstore.JSONCodec, tt.command)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// the record must survive the write-ahead log
+			encoded, err := json.Marshal(record)
+			if err != nil {
+				t.Fatalf("unable to marshal record: %v", err)
+			}
+			replayed := new(commandRecord)
+			if err = json.Unmarshal(encoded, replayed); err != nil {
+				t.Fatalf("unable to unmarshal record: %v", err)
+			}
+
+			payload, codec := (&bufferedCommand{commandRecord: replayed}).RawPayload()
+			if !bytes.Equal(payload, tt.wantPayload) {
+				t.Errorf("unexpected payload want: %s, got: %s", tt.wantPayload, payload)
+			}
+			if codec != tt.wantCodec {
+				t.Errorf("unexpected codec want: %q, got: %q", tt.wantCodec, codec)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package buffer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// record is a single buffered push
+type record struct {
+	Aggregates []*aggregateRecord `json:"aggregates"`
+}
+
+type aggregateRecord struct {
+	ID       eventstore.TextSubjects `json:"id"`
+	Tenant   string                  `json:"tenant,omitempty"`
+	Commands []*commandRecord        `json:"commands"`
+}
+
+type commandRecord struct {
+	Action         eventstore.TextSubjects `json:"action"`
+	Revision       uint16                  `json:"revision"`
+	Payload        []byte                  `json:"payload,omitempty"`
+	Codec          string                  `json:"codec,omitempty"`
+	Metadata       *eventstore.Metadata    `json:"metadata,omitempty"`
+	IdempotencyKey string                  `json:"idempotencyKey"`
+}
+
+func newRecord(ctx context.Context, codec eventstore.Codec, aggregates []eventstore.Aggregate) (_ *record, err error) {
+	record := &record{
+		Aggregates: make([]*aggregateRecord, len(aggregates)),
+	}
+	for i, aggregate := range aggregates {
+		record.Aggregates[i] = &aggregateRecord{
+			ID:       aggregate.ID(),
+			Tenant:   eventstore.TenantOf(ctx, aggregate),
+			Commands: make([]*commandRecord, len(aggregate.Commands())),
+		}
+		for j, command := range aggregate.Commands() {
+			if record.Aggregates[i].Commands[j], err = newCommandRecord(ctx, codec, command); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return record, nil
+}
+
+func newCommandRecord(ctx context.Context, codec eventstore.Codec, command eventstore.Command) (_ *commandRecord, err error) {
+	record := &commandRecord{
+		Action:   command.Action(),
+		Revision: command.Revision(),
+	}
+
+	// the payload is replayed as is, see [bufferedCommand.RawPayload]
+	if raw, ok := command.(eventstore.RawPayloadCommand); ok {
+		record.Payload, record.Codec = raw.RawPayload()
+	} else if command.Payload() != nil {
+		if record.Payload, err = codec.Marshal(command.Payload()); err != nil {
+			logger.ErrorContext(ctx, "marshal payload failed", "cause", err, "action", command.Action().Join("."))
+			return nil, err
+		}
+		record.Codec = codec.Name()
+	}
+
+	// the context is not available during the replay
+	var metadata *eventstore.Metadata
+	if withMetadata, ok := command.(eventstore.CommandMetadata); ok {
+		metadata = withMetadata.Metadata()
+	}
+	record.Metadata = eventstore.StampMetadata(ctx, metadata)
+
+	// only idempotent commands are buffered, see [bufferable]
+	record.IdempotencyKey = command.(eventstore.IdempotentCommand).IdempotencyKey()
+
+	return record, nil
+}
+
+func (r *record) commands() (count int) {
+	for _, aggregate := range r.Aggregates {
+		count += len(aggregate.Commands)
+	}
+	return count
+}
+
+// aggregates maps the record to the aggregates which are replayed
+func (r *record) aggregates() []eventstore.Aggregate {
+	aggregates := make([]eventstore.Aggregate, len(r.Aggregates))
+	for i, aggregate := range r.Aggregates {
+		aggregates[i] = &bufferedAggregate{aggregateRecord: aggregate}
+	}
+	return aggregates
+}
+
+var (
+	_ eventstore.Aggregate       = (*bufferedAggregate)(nil)
+	_ eventstore.TenantAggregate = (*bufferedAggregate)(nil)
+)
+
+type bufferedAggregate struct {
+	*aggregateRecord
+}
+
+// ID implements [eventstore.Aggregate]
+func (a *bufferedAggregate) ID() eventstore.TextSubjects {
+	return a.aggregateRecord.ID
+}
+
+// Commands implements [eventstore.Aggregate]
+func (a *bufferedAggregate) Commands() []eventstore.Command {
+	commands := make([]eventstore.Command, len(a.aggregateRecord.Commands))
+	for i, command := range a.aggregateRecord.Commands {
+		commands[i] = &bufferedCommand{commandRecord: command}
+	}
+	return commands
+}
+
+// Tenant implements [eventstore.TenantAggregate]
+func (a *bufferedAggregate) Tenant() string {
+	return a.aggregateRecord.Tenant
+}
+
+// CurrentSequence implements [eventstore.Aggregate]
+// buffered pushes don't expect a sequence
+func (*bufferedAggregate) CurrentSequence() *uint32 {
+	return nil
+}
+
+var (
+	_ eventstore.Command           = (*bufferedCommand)(nil)
+	_ eventstore.CommandMetadata   = (*bufferedCommand)(nil)
+	_ eventstore.IdempotentCommand = (*bufferedCommand)(nil)
+	_ eventstore.RawPayloadCommand = (*bufferedCommand)(nil)
+)
+
+type bufferedCommand struct {
+	*commandRecord
+}
+
+// Action implements [eventstore.Command]
+func (c *bufferedCommand) Action() eventstore.TextSubjects {
+	return c.commandRecord.Action
+}
+
+// Revision implements [eventstore.Command]
+func (c *bufferedCommand) Revision() uint16 {
+	return c.commandRecord.Revision
+}
+
+// Payload implements [eventstore.Command]
+// the payload is already encoded, json payloads are returned as [json.RawMessage]
+func (c *bufferedCommand) Payload() any {
+	if len(c.commandRecord.Payload) == 0 {
+		return nil
+	}
+	if c.commandRecord.Codec == eventstore.JSONCodec.Name() {
+		return json.RawMessage(c.commandRecord.Payload)
+	}
+	return c.commandRecord.Payload
+}
+
+// RawPayload implements [eventstore.RawPayloadCommand]
+func (c *bufferedCommand) RawPayload() ([]byte, string) {
+	return c.commandRecord.Payload, c.commandRecord.Codec
+}
+
+// SetSequence implements [eventstore.Command]
+func (*bufferedCommand) SetSequence(uint32) {}
+
+// SetCreationDate implements [eventstore.Command]
+func (*bufferedCommand) SetCreationDate(time.Time) {}
+
+// Metadata implements [eventstore.CommandMetadata]
+func (c *bufferedCommand) Metadata() *eventstore.Metadata {
+	return c.commandRecord.Metadata
+}
+
+// IdempotencyKey implements [eventstore.IdempotentCommand]
+func (c *bufferedCommand) IdempotencyKey() string {
+	return c.commandRecord.IdempotencyKey
+}
+
+// SetDeduplicated implements [eventstore.IdempotentCommand]
+func (*bufferedCommand) SetDeduplicated() {}
@@ -0,0 +1,295 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const (
+	logFile      = "buffer.log"
+	offsetFile   = "buffer.offset"
+	rejectedFile = "buffer.rejected"
+)
+
+// wal is an append-only log of records, one json encoded record per line.
+// The offset of the first record which was not replayed is stored in a separate file.
+type wal struct {
+	dir string
+
+	mu     sync.Mutex
+	file   *os.File
+	offset int64
+	size   int64
+	stats  Backlog
+}
+
+func openWAL(dir string) (_ *wal, err error) {
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		logger.Error("create buffer directory failed", "cause", err, "dir", dir)
+		return nil, err
+	}
+
+	log := &wal{dir: dir}
+	log.file, err = os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		logger.Error("open buffer failed", "cause", err, "dir", dir)
+		return nil, err
+	}
+
+	// the log file might have been created
+	if err = log.syncDir(); err != nil {
+		log.file.Close()
+		return nil, err
+	}
+
+	if err = log.init(); err != nil {
+		log.file.Close()
+		return nil, err
+	}
+
+	return log, nil
+}
+
+// init reads the offset and calculates the backlog of the records after the offset
+func (log *wal) init() error {
+	offset, err := os.ReadFile(filepath.Join(log.dir, offsetFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error("read buffer offset failed", "cause", err)
+		return err
+	}
+	if len(offset) > 0 {
+		if log.offset, err = strconv.ParseInt(string(offset), 10, 64); err != nil {
+			logger.Error("parse buffer offset failed", "cause", err)
+			return err
+		}
+	}
+
+	stat, err := log.file.Stat()
+	if err != nil {
+		logger.Error("stat buffer failed", "cause", err)
+		return err
+	}
+	log.size = stat.Size()
+	if log.offset > log.size {
+		// the log was truncated but the offset was not reset
+		log.offset = 0
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(log.file, log.offset, log.size-log.offset))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) == 0 {
+				return nil
+			}
+			// an incomplete line is the result of a crash during append
+			log.size = log.offset + log.stats.Bytes
+			return log.file.Truncate(log.size)
+		}
+		if err != nil {
+			logger.Error("read buffer failed", "cause", err)
+			return err
+		}
+		record := new(record)
+		if err = json.Unmarshal(line, record); err != nil {
+			logger.Error("unmarshal buffered push failed", "cause", err)
+			return err
+		}
+		log.stats.Pushes++
+		log.stats.Commands += record.commands()
+		log.stats.Bytes += int64(len(line))
+	}
+}
+
+func (log *wal) backlog() Backlog {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return log.stats
+}
+
+// append writes the record to the end of the log
+// the log is synced before the function returns
+func (log *wal) append(record *record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.Error("marshal buffered push failed", "cause", err)
+		return err
+	}
+	line = append(line, '\n')
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return ErrClosed
+	}
+	if _, err = log.file.Write(line); err != nil {
+		logger.Error("write buffer failed", "cause", err)
+		return err
+	}
+	if err = log.file.Sync(); err != nil {
+		logger.Error("sync buffer failed", "cause", err)
+		return err
+	}
+
+	log.size += int64(len(line))
+	log.stats.Pushes++
+	log.stats.Commands += record.commands()
+	log.stats.Bytes += int64(len(line))
+	return nil
+}
+
+// next returns the first record which was not replayed
+// and the offset of the following record
+// the record is nil if all records were replayed
+func (log *wal) next() (_ *record, next int64, err error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return nil, 0, ErrClosed
+	}
+	if log.offset >= log.size {
+		return nil, log.offset, nil
+	}
+
+	line, err := bufio.NewReader(io.NewSectionReader(log.file, log.offset, log.size-log.offset)).ReadBytes('\n')
+	if err != nil {
+		logger.Error("read buffer failed", "cause", err)
+		return nil, 0, err
+	}
+	record := new(record)
+	if err = json.Unmarshal(line, record); err != nil {
+		logger.Error("unmarshal buffered push failed", "cause", err)
+		return nil, 0, err
+	}
+
+	return record, log.offset + int64(len(line)), nil
+}
+
+// commit stores the offset of the next record after the record was replayed
+func (log *wal) commit(next int64, record *record) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if err := log.writeOffset(next); err != nil {
+		return err
+	}
+
+	log.stats.Pushes--
+	log.stats.Commands -= record.commands()
+	log.stats.Bytes -= next - log.offset
+	log.offset = next
+	return nil
+}
+
+// compact truncates the log if all records were replayed
+func (log *wal) compact() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil || log.offset == 0 || log.offset < log.size {
+		return nil
+	}
+
+	if err := log.file.Truncate(0); err != nil {
+		logger.Error("truncate buffer failed", "cause", err)
+		return err
+	}
+	// the offset must not be reset before the records are removed
+	if err := log.file.Sync(); err != nil {
+		logger.Error("sync buffer failed", "cause", err)
+		return err
+	}
+	if err := log.writeOffset(0); err != nil {
+		return err
+	}
+	log.offset, log.size = 0, 0
+	return nil
+}
+
+// reject appends the record to the rejected log
+func (log *wal) reject(record *record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		logger.Error("marshal rejected push failed", "cause", err)
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(log.dir, rejectedFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		logger.Error("open rejected log failed", "cause", err)
+		return err
+	}
+	defer file.Close()
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		logger.Error("write rejected log failed", "cause", err)
+		return err
+	}
+	return file.Sync()
+}
+
+// writeOffset replaces the offset file atomically
+// the file and the directory are synced so the offset survives a crash
+func (log *wal) writeOffset(offset int64) error {
+	path := filepath.Join(log.dir, offsetFile)
+	file, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		logger.Error("open buffer offset failed", "cause", err)
+		return err
+	}
+	if _, err = file.Write(strconv.AppendInt(nil, offset, 10)); err != nil {
+		file.Close()
+		logger.Error("write buffer offset failed", "cause", err)
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		logger.Error("sync buffer offset failed", "cause", err)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		logger.Error("close buffer offset failed", "cause", err)
+		return err
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		logger.Error("replace buffer offset failed", "cause", err)
+		return err
+	}
+	return log.syncDir()
+}
+
+// syncDir persists the entries of the directory, e.g. a renamed file
+func (log *wal) syncDir() error {
+	dir, err := os.Open(log.dir)
+	if err != nil {
+		logger.Error("open buffer directory failed", "cause", err)
+		return err
+	}
+	defer dir.Close()
+
+	if err = dir.Sync(); err != nil {
+		logger.Error("sync buffer directory failed", "cause", err)
+		return err
+	}
+	return nil
+}
+
+func (log *wal) close() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return nil
+	}
+	err := log.file.Close()
+	log.file = nil
+	return err
+}
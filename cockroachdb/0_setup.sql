@@ -1,6 +1,4 @@
-CREATE SCHEMA IF NOT EXISTS eventstore;
-
-CREATE TABLE IF NOT EXISTS eventstore.events (
+CREATE TABLE IF NOT EXISTS {{schema}}.events (
     id UUID NOT NULL DEFAULT gen_random_uuid()
 
     , "aggregate" TEXT[] NOT NULL
@@ -18,12 +16,12 @@ CREATE TABLE IF NOT EXISTS eventstore.events (
     , UNIQUE ("aggregate", "sequence")
 );
 
-CREATE TABLE IF NOT EXISTS eventstore.actions (
+CREATE TABLE IF NOT EXISTS {{schema}}.actions (
     "event" UUID
     , "action" TEXT
     , depth INT2
 
     , PRIMARY KEY (event, action, depth)
-    , FOREIGN KEY ("event") REFERENCES eventstore.events ON DELETE CASCADE
+    , FOREIGN KEY ("event") REFERENCES {{schema}}.events ON DELETE CASCADE
     , INDEX search ("action", depth)
 );
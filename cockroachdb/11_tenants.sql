@@ -0,0 +1,9 @@
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE {{schema}}.unique_constraints ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE {{schema}}.data_keys ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS tenant_aggregate_sequence ON {{schema}}.events (tenant, "aggregate", "sequence");
+DROP INDEX IF EXISTS {{schema}}.events@events_aggregate_sequence_key CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS tenant_subject ON {{schema}}.data_keys (tenant, subject);
+DROP INDEX IF EXISTS {{schema}}.data_keys@data_keys_subject_key CASCADE;
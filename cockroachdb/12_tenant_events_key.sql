@@ -0,0 +1,2 @@
+-- the previous primary key is kept as unique index by cockroachdb
+ALTER TABLE {{schema}}.events ALTER PRIMARY KEY USING COLUMNS (tenant, id);
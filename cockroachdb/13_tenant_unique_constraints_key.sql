@@ -0,0 +1 @@
+ALTER TABLE {{schema}}.unique_constraints ALTER PRIMARY KEY USING COLUMNS (tenant, namespace, "value");
@@ -0,0 +1,3 @@
+-- the previous primary key is kept as unique index by cockroachdb
+-- but values must only be unique per tenant
+DROP INDEX IF EXISTS {{schema}}.unique_constraints@unique_constraints_namespace_value_key CASCADE;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS payload_ref ON {{schema}}.events (payload_ref) WHERE payload_ref IS NOT NULL;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS {{schema}}.orphaned_blobs (
+    ref TEXT NOT NULL
+    , orphaned_at TIMESTAMPTZ NOT NULL DEFAULT now()
+
+    , PRIMARY KEY (ref)
+);
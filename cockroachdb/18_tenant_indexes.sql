@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS tenant_correlation ON {{schema}}.events (tenant, correlation_id) WHERE correlation_id IS NOT NULL;
+DROP INDEX IF EXISTS {{schema}}.events@correlation;
+
+CREATE INDEX IF NOT EXISTS tenant_idempotency ON {{schema}}.events (tenant, idempotency_key, created_at) WHERE idempotency_key IS NOT NULL;
+DROP INDEX IF EXISTS {{schema}}.events@idempotency;
@@ -0,0 +1,2 @@
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS metadata JSONB;
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS correlation_id TEXT AS (metadata->>'correlationId') STORED;
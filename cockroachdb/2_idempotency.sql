@@ -0,0 +1 @@
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
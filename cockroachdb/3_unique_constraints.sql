@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS {{schema}}.unique_constraints (
+    namespace TEXT NOT NULL
+    , "value" TEXT NOT NULL
+
+    , PRIMARY KEY (namespace, "value")
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS {{schema}}.data_keys (
+    id UUID NOT NULL DEFAULT gen_random_uuid()
+    , subject TEXT NOT NULL
+    , "key" BYTES NOT NULL
+    , created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+
+    , PRIMARY KEY (id)
+    , UNIQUE (subject)
+);
+
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS data_subject TEXT;
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS data_key_id UUID;
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS encrypted_payload BYTES;
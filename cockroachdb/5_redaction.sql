@@ -0,0 +1 @@
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS redacted_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS payload_hash BYTES;
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS hash BYTES;
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS previous_hash BYTES;
@@ -0,0 +1,2 @@
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS key_id TEXT;
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS signature BYTES;
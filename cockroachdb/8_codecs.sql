@@ -0,0 +1,2 @@
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS codec TEXT;
+ALTER TABLE {{schema}}.events ADD COLUMN IF NOT EXISTS raw_payload BYTES;
@@ -0,0 +1,292 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	crdb "github.com/cockroachdb/cockroach-go/v2/crdb/crdbpgxv5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var (
+	_ eventstore.Eventstore = (*Batcher)(nil)
+
+	// ErrBatcherClosed is returned if [Batcher.Push] is called after [Batcher.Close]
+	ErrBatcherClosed = errors.New("batcher closed")
+)
+
+// Batcher coalesces concurrent pushes into a single transaction.
+// Every push is executed inside its own savepoint, so a push which fails
+// doesn't affect the other pushes of the batch.
+// Only errors which require a retry of the transaction affect all pushes of the batch.
+//
+// Filter and Ready are passed to the underlying store.
+type Batcher struct {
+	*CockroachDB
+
+	window       time.Duration
+	maxBatchSize int
+
+	requests chan *batchRequest
+	// mu prevents requests from being sent after the batcher was closed
+	mu       sync.RWMutex
+	isClosed bool
+	closed   chan struct{}
+	done     chan struct{}
+}
+
+type batchRequest struct {
+	ctx      context.Context
+	indexes  *aggregateIndexes
+	commands []*command
+	err      chan error
+}
+
+// NewBatcher starts a batcher which pushes using store
+// [Batcher.Close] must be called to stop the batcher
+func NewBatcher(store *CockroachDB, opts ...batcherOpt) *Batcher {
+	batcher := &Batcher{
+		CockroachDB:  store,
+		window:       5 * time.Millisecond,
+		maxBatchSize: 100,
+		closed:       make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(batcher)
+	}
+	batcher.requests = make(chan *batchRequest, batcher.maxBatchSize)
+
+	go batcher.run()
+
+	return batcher
+}
+
+type batcherOpt func(*Batcher)
+
+// WithBatchWindow defines how long the batcher waits for further pushes
+// after the first push of a batch arrived. Default is 5ms.
+func WithBatchWindow(window time.Duration) batcherOpt {
+	return func(batcher *Batcher) {
+		batcher.window = window
+	}
+}
+
+// WithMaxBatchSize limits the amount of pushes executed in a single transaction
+// Default is 100.
+func WithMaxBatchSize(size int) batcherOpt {
+	return func(batcher *Batcher) {
+		if size > 0 {
+			batcher.maxBatchSize = size
+		}
+	}
+}
+
+// Push implements [eventstore.Eventstore]
+// The push is executed together with the pushes which arrive within the window
+func (batcher *Batcher) Push(ctx context.Context, aggregates ...eventstore.Aggregate) error {
+	request, close, err := batcher.newRequest(ctx, aggregates)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	if err = batcher.send(ctx, request); err != nil {
+		return err
+	}
+
+	// the commands are in use until the batch is finished
+	// therefore the result is awaited even if ctx is done
+	return <-request.err
+}
+
+// newRequest prepares the commands of the aggregates
+// close releases the commands after the request was executed
+func (batcher *Batcher) newRequest(ctx context.Context, aggregates []eventstore.Aggregate) (request *batchRequest, close func(), err error) {
+	indexes := prepareIndexes(ctx, aggregates)
+
+	commands, close, err := batcher.commandsFromAggregates(ctx, aggregates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &batchRequest{
+		ctx:      ctx,
+		indexes:  indexes,
+		commands: commands,
+		err:      make(chan error, 1),
+	}, close, nil
+}
+
+func (batcher *Batcher) send(ctx context.Context, request *batchRequest) error {
+	batcher.mu.RLock()
+	defer batcher.mu.RUnlock()
+
+	if batcher.isClosed {
+		return ErrBatcherClosed
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case batcher.requests <- request:
+		return nil
+	}
+}
+
+// Close stops the batcher after the pending pushes are executed
+func (batcher *Batcher) Close() {
+	batcher.mu.Lock()
+	if !batcher.isClosed {
+		batcher.isClosed = true
+		close(batcher.closed)
+	}
+	batcher.mu.Unlock()
+
+	<-batcher.done
+}
+
+func (batcher *Batcher) run() {
+	defer close(batcher.done)
+
+	for {
+		select {
+		case <-batcher.closed:
+			batcher.drain()
+			return
+		case request := <-batcher.requests:
+			batcher.execute(batcher.collect(request))
+		}
+	}
+}
+
+// collect waits for further requests until the window elapsed or the batch is full
+func (batcher *Batcher) collect(first *batchRequest) []*batchRequest {
+	batch := make([]*batchRequest, 1, batcher.maxBatchSize)
+	batch[0] = first
+
+	timer := time.NewTimer(batcher.window)
+	defer timer.Stop()
+
+	for len(batch) < batcher.maxBatchSize {
+		select {
+		case request := <-batcher.requests:
+			batch = append(batch, request)
+		case <-timer.C:
+			return batch
+		case <-batcher.closed:
+			return batch
+		}
+	}
+	return batch
+}
+
+// drain executes the requests which were sent before the batcher was closed
+func (batcher *Batcher) drain() {
+	for {
+		select {
+		case request := <-batcher.requests:
+			batcher.execute(batcher.collect(request))
+		default:
+			return
+		}
+	}
+}
+
+func (batcher *Batcher) execute(batch []*batchRequest) {
+	// requests whose context is already done are not executed
+	pending := make([]*batchRequest, 0, len(batch))
+	for _, request := range batch {
+		if err := request.ctx.Err(); err != nil {
+			request.err <- err
+			continue
+		}
+		pending = append(pending, request)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	// the batch must not be aborted if the context of a single request is done
+	ctx := context.WithoutCancel(pending[0].ctx)
+	results := make([]error, len(pending))
+
+	err := batcher.executeBatch(ctx, pending, results)
+	for i, request := range pending {
+		if err != nil {
+			request.err <- err
+			continue
+		}
+		request.err <- results[i]
+	}
+}
+
+func (batcher *Batcher) executeBatch(ctx context.Context, batch []*batchRequest, results []error) error {
+	conn, err := batcher.acquirePushConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return crdb.ExecuteTx(ctx, conn, pushTxOptions, func(tx pgx.Tx) (err error) {
+		// the transaction might be retried
+		var inTxOffset int
+		for i, request := range batch {
+			request.indexes.inTxOffset = inTxOffset
+			results[i], err = batcher.executeRequest(context.WithoutCancel(request.ctx), tx, request)
+			if err != nil {
+				return err
+			}
+			if results[i] == nil {
+				inTxOffset += len(request.commands)
+			}
+		}
+		return nil
+	})
+}
+
+// executeRequest pushes the commands of the request inside a savepoint
+// the savepoint is rolled back if the request failed and the error is returned as requestErr.
+// err is only returned if the whole batch must be aborted,
+// e.g. if the transaction must be retried or the savepoint could not be handled.
+func (batcher *Batcher) executeRequest(ctx context.Context, tx pgx.Tx, request *batchRequest) (requestErr, err error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "create savepoint failed", "cause", err)
+		return nil, err
+	}
+
+	if requestErr = batcher.pushInTx(ctx, savepoint, request.indexes, request.commands); requestErr != nil {
+		if isRetryableError(requestErr) {
+			return nil, requestErr
+		}
+		if err = savepoint.Rollback(ctx); err != nil {
+			logger.ErrorContext(ctx, "rollback savepoint failed", "cause", err)
+			return nil, err
+		}
+		return requestErr, nil
+	}
+
+	if err = savepoint.Commit(ctx); err != nil {
+		logger.ErrorContext(ctx, "release savepoint failed", "cause", err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// retryableErrorCode is the sql state of errors which require a retry of the transaction
+const retryableErrorCode = "40001"
+
+// isRetryableError returns true if the transaction must be retried because of err,
+// the savepoints can't be rolled back in this case so the whole batch is aborted and retried.
+// Other errors only fail the push which caused them.
+func isRetryableError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == retryableErrorCode
+}
@@ -0,0 +1,264 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var _ eventstore.TestEventstore = (*testBatcher)(nil)
+
+type testBatcher struct {
+	*Batcher
+}
+
+// After implements eventstore.TestEventstore
+func (*testBatcher) After(ctx context.Context, t testing.TB) error {
+	return nil
+}
+
+// Before implements eventstore.TestEventstore
+func (*testBatcher) Before(ctx context.Context, t testing.TB) error {
+	return store.Before(ctx, t)
+}
+
+func newTestBatcher(t testing.TB) *testBatcher {
+	batcher := NewBatcher(store.CockroachDB)
+	t.Cleanup(batcher.Close)
+	return &testBatcher{Batcher: batcher}
+}
+
+func Benchmark_Batcher_ParallelSameAggregate(b *testing.B) {
+	b.Run("Benchmark_Batcher_ParallelSameAggregate", func(b *testing.B) {
+		eventstore.PushParallelOnSameAggregate(context.Background(), b, newTestBatcher(b))
+	})
+}
+
+func Benchmark_Batcher_ParallelDifferentAggregate(b *testing.B) {
+	b.Run("Benchmark_Batcher_ParallelDifferentAggregate", func(b *testing.B) {
+		eventstore.PushParallelOnDifferentAggregates(context.Background(), b, newTestBatcher(b))
+	})
+}
+
+func Test_Batcher_Push_Compliance(t *testing.T) {
+	eventstore.PushComplianceTests(context.Background(), t, newTestBatcher(t))
+}
+
+func Test_Batcher_AppendCondition_Compliance(t *testing.T) {
+	eventstore.AppendConditionComplianceTests(context.Background(), t, newTestBatcher(t))
+}
+
+// sequencedTestAggregate expects the aggregate to be at sequence
+type sequencedTestAggregate struct {
+	*testAggregate
+	sequence uint32
+}
+
+// CurrentSequence implements eventstore.Aggregate.
+func (a *sequencedTestAggregate) CurrentSequence() *uint32 {
+	return &a.sequence
+}
+
+func newBatchTestAggregate(id string, commandCount int) *testAggregate {
+	aggregate := &testAggregate{id: eventstore.TextSubjects{"batch", eventstore.TextSubject(id)}}
+	for range commandCount {
+		aggregate.commands = append(aggregate.commands, &testCommand{
+			testAction: &testAction{action: eventstore.TextSubjects{"batch", eventstore.TextSubject(id), "added"}, revision: 1},
+			payload:    map[string]string{"id": id},
+		})
+	}
+	return aggregate
+}
+
+// restartCounter counts the retries of transactions executed by [crdb.ExecuteTx]
+type restartCounter struct {
+	restarts atomic.Int32
+}
+
+// TraceQueryStart implements [pgx.QueryTracer]
+func (c *restartCounter) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if data.SQL == "ROLLBACK TO SAVEPOINT cockroach_restart" {
+		c.restarts.Add(1)
+	}
+	return ctx
+}
+
+// TraceQueryEnd implements [pgx.QueryTracer]
+func (*restartCounter) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+// newRetryingTestBatcher returns a batcher whose transactions fail with retry errors
+// the first times they are executed, see inject_retry_errors_enabled of cockroachdb
+func newRetryingTestBatcher(t *testing.T, counter *restartCounter) *Batcher {
+	config := store.client.Config()
+	config.ConnConfig.Tracer = counter
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "SET inject_retry_errors_enabled = true")
+		return err
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unable to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	batcher := NewBatcher(New(&Config{Pool: pool}))
+	t.Cleanup(batcher.Close)
+	return batcher
+}
+
+func Test_Batcher_execute(t *testing.T) {
+	type storedEvent struct {
+		aggregate []string
+		inTxOrder int
+	}
+	tests := []struct {
+		name          string
+		injectRetries bool
+	}{
+		{
+			name: "isolated request errors",
+		},
+		{
+			name:          "retried transaction",
+			injectRetries: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := store.Before(ctx, t); err != nil {
+				t.Fatalf("unable to execute store.Before: %v", err)
+			}
+
+			counter := new(restartCounter)
+			batcher := newTestBatcher(t).Batcher
+			if tt.injectRetries {
+				batcher = newRetryingTestBatcher(t, counter)
+			}
+
+			aggregates := [][]eventstore.Aggregate{
+				{newBatchTestAggregate("1", 2)},
+				// the aggregate doesn't exist, therefore the sequence doesn't match
+				{&sequencedTestAggregate{testAggregate: newBatchTestAggregate("2", 1), sequence: 5}},
+				{newBatchTestAggregate("3", 1)},
+			}
+			batch := make([]*batchRequest, len(aggregates))
+			for i, aggregate := range aggregates {
+				request, close, err := batcher.newRequest(ctx, aggregate)
+				if err != nil {
+					t.Fatalf("unable to prepare request: %v", err)
+				}
+				defer close()
+				batch[i] = request
+			}
+
+			batcher.execute(batch)
+
+			wantErrs := []error{nil, eventstore.ErrSequenceNotMatched, nil}
+			for i, request := range batch {
+				if err := <-request.err; !errors.Is(err, wantErrs[i]) {
+					t.Errorf("unexpected error of request %d want: %v, got: %v", i, wantErrs[i], err)
+				}
+			}
+			if tt.injectRetries && counter.restarts.Load() == 0 {
+				t.Error("transaction was not retried")
+			}
+
+			rows, err := store.client.Query(ctx, `SELECT "aggregate", in_tx_order, "position"::STRING FROM eventstore.events ORDER BY in_tx_order`)
+			if err != nil {
+				t.Fatalf("unable to query events: %v", err)
+			}
+			defer rows.Close()
+
+			var (
+				got       []storedEvent
+				positions = make(map[string]bool)
+			)
+			for rows.Next() {
+				var (
+					event    storedEvent
+					position string
+				)
+				if err = rows.Scan(&event.aggregate, &event.inTxOrder, &position); err != nil {
+					t.Fatalf("unable to scan event: %v", err)
+				}
+				got = append(got, event)
+				positions[position] = true
+			}
+			if err = rows.Err(); err != nil {
+				t.Fatalf("unable to read events: %v", err)
+			}
+
+			want := []storedEvent{
+				{aggregate: []string{"batch", "1"}, inTxOrder: 0},
+				{aggregate: []string{"batch", "1"}, inTxOrder: 1},
+				{aggregate: []string{"batch", "3"}, inTxOrder: 2},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected events want:\n%v\ngot:\n%v", want, got)
+			}
+			if len(positions) != 1 {
+				t.Errorf("events of a batch must share the position of the transaction, got: %v", positions)
+			}
+		})
+	}
+}
+
+func Test_Batcher_closed(t *testing.T) {
+	batcher := NewBatcher(New(&Config{}))
+	batcher.Close()
+
+	if err := batcher.Push(context.Background()); !errors.Is(err, ErrBatcherClosed) {
+		t.Errorf("expected %v, got: %v", ErrBatcherClosed, err)
+	}
+}
+
+func Test_isRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "retry",
+			err:  &pgconn.PgError{Code: "40001"},
+			want: true,
+		},
+		{
+			name: "wrapped retry",
+			err:  fmt.Errorf("push failed: %w", &pgconn.PgError{Code: "40001"}),
+			want: true,
+		},
+		{
+			name: "constraint violation",
+			err:  &pgconn.PgError{Code: "23505"},
+			want: false,
+		},
+		{
+			name: "request error",
+			err:  eventstore.ErrSequenceNotMatched,
+			want: false,
+		},
+		{
+			name: "other error",
+			err:  errors.New("payload could not be encoded"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.want {
+				t.Errorf("isRetryableError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	crdb "github.com/cockroachdb/cockroach-go/v2/crdb/crdbpgxv5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// ErrMissingBlobStore is returned if an offloaded payload is read from a store without [WithBlobStore]
+var ErrMissingBlobStore = errors.New("payload is offloaded to a blob store but none is configured")
+
+// offloadPayloads writes the payloads exceeding limit to the blob store
+// the payload is offloaded as it's stored, i.e. compressed and encrypted
+// it runs on each retry of the transaction because the encrypted payload changes.
+// claim is called with the refs before the payloads are written, see [CockroachDB.claimBlobs]
+func offloadPayloads(ctx context.Context, blobs eventstore.BlobStore, limit int, commands []*command, claim func(ctx context.Context, refs []string) error) error {
+	offloaded := make([]*command, 0, len(commands))
+	refs := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		cmd.payloadRef = ""
+		if len(cmd.offloadedPayload()) < limit {
+			continue
+		}
+		cmd.payloadRef = eventstore.BlobKey(cmd.offloadedPayload())
+		offloaded = append(offloaded, cmd)
+		refs = append(refs, cmd.payloadRef)
+	}
+	if len(offloaded) == 0 {
+		return nil
+	}
+
+	if err := claim(ctx, refs); err != nil {
+		return err
+	}
+	for _, cmd := range offloaded {
+		if err := blobs.Put(ctx, cmd.payloadRef, cmd.offloadedPayload()); err != nil {
+			logger.ErrorContext(ctx, "offload payload failed", "cause", err, "action", cmd.Action().Join("."))
+			return err
+		}
+	}
+	return nil
+}
+
+// offloadedPayload is the payload as it's stored
+func (cmd *command) offloadedPayload() []byte {
+	if cmd.encryptedPayload != nil {
+		return cmd.encryptedPayload
+	}
+	return cmd.storedPayload()
+}
+
+var (
+	// blobs are content-addressed and therefore shared by all tenants
+	orphanBlobsStmt = `INSERT INTO {{schema}}.orphaned_blobs (ref) SELECT r.ref FROM unnest($1::TEXT[]) AS r(ref) WHERE NOT EXISTS (SELECT 1 FROM {{schema}}.events e WHERE e.payload_ref = r.ref) ON CONFLICT (ref) DO NOTHING`
+	// the row lock of the orphaned blob serializes pushes and [CockroachDB.CollectBlobs]
+	claimBlobsStmt         = `DELETE FROM {{schema}}.orphaned_blobs WHERE ref = ANY($1::TEXT[])`
+	collectableBlobsStmt   = `SELECT ref FROM {{schema}}.orphaned_blobs WHERE orphaned_at < now() - $1::INTERVAL`
+	lockOrphanedBlobStmt   = `SELECT ref FROM {{schema}}.orphaned_blobs WHERE ref = $1 FOR UPDATE`
+	isBlobReferencedStmt   = `SELECT EXISTS (SELECT 1 FROM {{schema}}.events WHERE payload_ref = $1)`
+	deleteOrphanedBlobStmt = `DELETE FROM {{schema}}.orphaned_blobs WHERE ref = $1`
+)
+
+// orphanBlobs records the refs which are not referenced by events anymore
+// the blobs are deleted by [CockroachDB.CollectBlobs]
+func (store *CockroachDB) orphanBlobs(ctx context.Context, tx pgx.Tx, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, store.qualify(orphanBlobsStmt), refs); err != nil {
+		logger.ErrorContext(ctx, "record orphaned blobs failed", "cause", err)
+		return err
+	}
+	return nil
+}
+
+// claimBlobs removes the refs from the orphaned blobs before the payloads are written.
+// It waits for a running [CockroachDB.CollectBlobs] of the refs,
+// so the payloads are written after the blobs were deleted.
+func (store *CockroachDB) claimBlobs(ctx context.Context, tx pgx.Tx, refs []string) error {
+	if _, err := tx.Exec(ctx, store.qualify(claimBlobsStmt), refs); err != nil {
+		logger.ErrorContext(ctx, "claim blobs failed", "cause", err)
+		return err
+	}
+	return nil
+}
+
+// CollectBlobs deletes the offloaded payloads of [WithBlobStore]
+// which were orphaned by [CockroachDB.RedactEvents] longer than gracePeriod ago.
+// The grace period allows events which were read before the redaction to fetch their payloads.
+// Blobs which are referenced again, e.g. by a push of the same payload, are kept.
+// The refs of the deleted blobs are returned.
+func (store *CockroachDB) CollectBlobs(ctx context.Context, gracePeriod time.Duration) (deleted []string, err error) {
+	if store.blobs == nil {
+		return nil, ErrMissingBlobStore
+	}
+
+	refs, err := store.orphanedBlobs(ctx, gracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := store.acquirePushConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	for _, ref := range refs {
+		var isDeleted bool
+		err = crdb.ExecuteTx(ctx, conn, pushTxOptions, func(tx pgx.Tx) (err error) {
+			isDeleted, err = store.collectBlob(ctx, tx, ref)
+			return err
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if isDeleted {
+			deleted = append(deleted, ref)
+		}
+	}
+	return deleted, nil
+}
+
+// orphanedBlobs returns the refs orphaned longer than gracePeriod ago
+func (store *CockroachDB) orphanedBlobs(ctx context.Context, gracePeriod time.Duration) ([]string, error) {
+	rows, err := store.client.Query(ctx, store.qualify(collectableBlobsStmt), gracePeriod)
+	if err != nil {
+		logger.ErrorContext(ctx, "query orphaned blobs failed", "cause", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err = rows.Scan(&ref); err != nil {
+			logger.ErrorContext(ctx, "scan of orphaned blobs failed", "cause", err)
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	if err = rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "read orphaned blobs failed", "cause", err)
+		return nil, err
+	}
+	return refs, nil
+}
+
+// collectBlob deletes the blob of ref if it's still orphaned and not referenced.
+// The blob is deleted while the orphaned blob is locked,
+// pushes of the same payload wait in [CockroachDB.claimBlobs] and write it again.
+func (store *CockroachDB) collectBlob(ctx context.Context, tx pgx.Tx, ref string) (isDeleted bool, err error) {
+	err = tx.QueryRow(ctx, store.qualify(lockOrphanedBlobStmt), ref).Scan(&ref)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// claimed by a push
+		return false, nil
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "lock orphaned blob failed", "cause", err, "ref", ref)
+		return false, err
+	}
+
+	var isReferenced bool
+	if err = tx.QueryRow(ctx, store.qualify(isBlobReferencedStmt), ref).Scan(&isReferenced); err != nil {
+		logger.ErrorContext(ctx, "check blob references failed", "cause", err, "ref", ref)
+		return false, err
+	}
+	if !isReferenced {
+		if err = store.blobs.Delete(ctx, ref); err != nil {
+			logger.ErrorContext(ctx, "delete offloaded payload failed", "cause", err, "ref", ref)
+			return false, err
+		}
+	}
+
+	if _, err = tx.Exec(ctx, store.qualify(deleteOrphanedBlobStmt), ref); err != nil {
+		logger.ErrorContext(ctx, "delete orphaned blob failed", "cause", err, "ref", ref)
+		return false, err
+	}
+	return !isReferenced, nil
+}
+
+// loadBlob fetches the offloaded payload and verifies its hash
+func loadBlob(ctx context.Context, blobs eventstore.BlobStore, ref string) ([]byte, error) {
+	if blobs == nil {
+		return nil, fmt.Errorf("%w: %q", ErrMissingBlobStore, ref)
+	}
+	payload, err := blobs.Get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err = eventstore.VerifyBlob(ref, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
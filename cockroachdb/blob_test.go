@@ -0,0 +1,224 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var _ eventstore.BlobStore = (*testBlobStore)(nil)
+
+type testBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func newTestBlobStore() *testBlobStore {
+	return &testBlobStore{blobs: make(map[string][]byte)}
+}
+
+// Put implements [eventstore.BlobStore]
+func (s *testBlobStore) Put(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = data
+	return nil
+}
+
+// Get implements [eventstore.BlobStore]
+func (s *testBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", eventstore.ErrBlobNotFound, key)
+	}
+	return data, nil
+}
+
+// Delete implements [eventstore.BlobStore]
+func (s *testBlobStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+func Test_BlobStore(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	blobs := newTestBlobStore()
+	offloading := New(&Config{Pool: store.client}, WithBlobStore(blobs, 1024))
+
+	if err := offloading.Push(ctx, largeDocumentAggregate("1")); err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	var ref string
+	if err := store.client.QueryRow(ctx, `SELECT payload_ref FROM eventstore.events WHERE payload IS NULL`).Scan(&ref); err != nil {
+		t.Fatalf("unable to query payload ref: %v", err)
+	}
+	if _, ok := blobs.blobs[ref]; !ok {
+		t.Fatalf("payload must be offloaded to %q", ref)
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("document"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+		},
+	}
+	for event, err := range offloading.FilterIter(ctx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		payload := make(map[string]string)
+		if err = event.UnmarshalPayload(&payload); err != nil {
+			t.Fatalf("unable to unmarshal payload: %v", err)
+		}
+		if !reflect.DeepEqual(payload, largeDocument()) {
+			t.Errorf("unexpected payload: %v", payload)
+		}
+	}
+
+	// retained events are read after the filter finished
+	filterCtx, cancel := context.WithCancel(ctx)
+	var retained []eventstore.Event
+	for event, err := range offloading.FilterIter(filterCtx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		retained = append(retained, eventstore.Clone(event))
+	}
+	cancel()
+	for _, event := range retained {
+		payload := make(map[string]string)
+		if err := event.UnmarshalPayload(&payload); err != nil {
+			t.Fatalf("unable to unmarshal payload of retained event: %v", err)
+		}
+	}
+
+	for event, err := range store.FilterIter(ctx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		if err = event.UnmarshalPayload(&map[string]string{}); !errors.Is(err, ErrMissingBlobStore) {
+			t.Errorf("expected %v, got: %v", ErrMissingBlobStore, err)
+		}
+	}
+
+	if _, err := offloading.RedactEvents(ctx, &Redaction{Filter: filter}); err != nil {
+		t.Fatalf("unable to redact events: %v", err)
+	}
+	if _, ok := blobs.blobs[ref]; !ok {
+		t.Errorf("payload of redacted event must be kept until it's collected %q", ref)
+	}
+	deleted, err := offloading.CollectBlobs(ctx, 0)
+	if err != nil {
+		t.Fatalf("unable to collect blobs: %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{ref}) {
+		t.Errorf("unexpected collected blobs want: %v, got: %v", []string{ref}, deleted)
+	}
+	if _, ok := blobs.blobs[ref]; ok {
+		t.Errorf("payload of redacted event must be deleted from %q", ref)
+	}
+}
+
+func Test_CollectBlobs(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	blobs := newTestBlobStore()
+	offloading := New(&Config{Pool: store.client}, WithBlobStore(blobs, 1024))
+
+	documentFilter := func(id string) *eventstore.Filter {
+		return &eventstore.Filter{
+			Queries: []*eventstore.FilterQuery{
+				{Subjects: []eventstore.Subject{eventstore.TextSubject("document"), eventstore.TextSubject(id), eventstore.MultiToken}},
+			},
+		}
+	}
+	readDocument := func(t *testing.T, id string) {
+		t.Helper()
+		for event, err := range offloading.FilterIter(ctx, documentFilter(id)) {
+			if err != nil {
+				t.Fatalf("unable to filter events: %v", err)
+			}
+			if err = event.UnmarshalPayload(&map[string]string{}); err != nil {
+				t.Errorf("unable to unmarshal payload of document %s: %v", id, err)
+			}
+		}
+	}
+	collect := func(t *testing.T, gracePeriod time.Duration, want []string) {
+		t.Helper()
+		deleted, err := offloading.CollectBlobs(ctx, gracePeriod)
+		if err != nil {
+			t.Fatalf("unable to collect blobs: %v", err)
+		}
+		if !reflect.DeepEqual(deleted, want) {
+			t.Errorf("unexpected collected blobs want: %v, got: %v", want, deleted)
+		}
+	}
+
+	// the documents have equal payloads and therefore share the blob
+	if err := offloading.Push(ctx, largeDocumentAggregate("1"), largeDocumentAggregate("2")); err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+	if len(blobs.blobs) != 1 {
+		t.Fatalf("documents must share the blob, got: %d blobs", len(blobs.blobs))
+	}
+	ref := slices.Collect(maps.Keys(blobs.blobs))[0]
+
+	t.Run("referenced by other event", func(t *testing.T) {
+		if _, err := offloading.RedactEvents(ctx, &Redaction{Filter: documentFilter("1")}); err != nil {
+			t.Fatalf("unable to redact events: %v", err)
+		}
+		collect(t, 0, nil)
+		if _, ok := blobs.blobs[ref]; !ok {
+			t.Fatal("blob referenced by other event must not be deleted")
+		}
+		readDocument(t, "2")
+	})
+
+	t.Run("grace period", func(t *testing.T) {
+		if _, err := offloading.RedactEvents(ctx, &Redaction{Filter: documentFilter("2")}); err != nil {
+			t.Fatalf("unable to redact events: %v", err)
+		}
+		collect(t, time.Hour, nil)
+		if _, ok := blobs.blobs[ref]; !ok {
+			t.Fatal("blob must be kept during the grace period")
+		}
+	})
+
+	t.Run("pushed again", func(t *testing.T) {
+		if err := offloading.Push(ctx, largeDocumentAggregate("3")); err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+		collect(t, 0, nil)
+		readDocument(t, "3")
+	})
+
+	t.Run("orphaned", func(t *testing.T) {
+		if _, err := offloading.RedactEvents(ctx, &Redaction{Filter: documentFilter("3")}); err != nil {
+			t.Fatalf("unable to redact events: %v", err)
+		}
+		collect(t, 0, []string{ref})
+		if _, ok := blobs.blobs[ref]; ok {
+			t.Error("orphaned blob must be deleted")
+		}
+	})
+}
@@ -0,0 +1,60 @@
+// verifychain walks the hash chains of the events stored by the cockroachdb eventstore
+// and reports the first broken link of each aggregate.
+//
+// Usage:
+//
+//	verifychain [-dsn <connection string>] [-schema <schema>] [-tenant <tenant>] [aggregate ...]
+//
+// Aggregates are passed as dot separated subjects, e.g. user.1.
+// If no aggregates are passed all events of the tenant are verified.
+// The exit code is 1 if a broken link was found.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/adlerhurst/eventstore/v2"
+	"github.com/adlerhurst/eventstore/v2/cockroachdb"
+)
+
+func main() {
+	dsn := flag.String("dsn", "postgresql://root@localhost:26257/eventstore?sslmode=disable", "connection string of the database")
+	schema := flag.String("schema", cockroachdb.DefaultSchema, "schema of the eventstore tables")
+	tenant := flag.String("tenant", "", "tenant of the events")
+	flag.Parse()
+
+	ctx := eventstore.WithTenant(context.Background(), *tenant)
+	pool, err := pgxpool.New(ctx, *dsn)
+	if err != nil {
+		log.Fatalf("unable to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	aggregates := make([]eventstore.TextSubjects, flag.NArg())
+	for i, arg := range flag.Args() {
+		for _, subject := range strings.Split(arg, ".") {
+			aggregates[i] = append(aggregates[i], eventstore.TextSubject(subject))
+		}
+	}
+
+	broken, err := cockroachdb.New(&cockroachdb.Config{Pool: pool, Schema: *schema}).VerifyHashChain(ctx, aggregates...)
+	if err != nil {
+		log.Fatalf("unable to verify hash chain: %v", err)
+	}
+	if len(broken) == 0 {
+		fmt.Println("hash chain intact")
+		return
+	}
+
+	for _, link := range broken {
+		fmt.Println("broken link:", link)
+	}
+	os.Exit(1)
+}
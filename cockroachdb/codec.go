@@ -0,0 +1,20 @@
+package cockroachdb
+
+import (
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// isJSON checks if the payloads of the codec are stored in the JSONB payload column
+// payloads of other codecs are stored in the raw_payload column
+func isJSON(codec string) bool {
+	return codec == eventstore.JSONCodec.Name()
+}
+
+// codecColumn returns the value of the codec column
+// json is stored as NULL like the events stored before codecs were introduced
+func codecColumn(codec string) any {
+	if isJSON(codec) {
+		return nil
+	}
+	return codec
+}
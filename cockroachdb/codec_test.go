@@ -0,0 +1,217 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var _ eventstore.Codec = (*gobCodec)(nil)
+
+type gobCodec struct{}
+
+// Name implements [eventstore.Codec]
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+// Marshal implements [eventstore.Codec]
+func (gobCodec) Marshal(object any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(object)
+	return buf.Bytes(), err
+}
+
+// Unmarshal implements [eventstore.Codec]
+func (gobCodec) Unmarshal(data []byte, object any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(object)
+}
+
+func Test_Codecs(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	gobStore := New(&Config{Pool: store.client}, WithCodec(gobCodec{}))
+
+	for _, s := range []*CockroachDB{store.CockroachDB, gobStore} {
+		err := s.Push(ctx, &testAggregate{
+			id: eventstore.TextSubjects{"user", "1"},
+			commands: []eventstore.Command{
+				&testCommand{
+					testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "changed"}, revision: 1},
+					payload:    map[string]string{"name": "gigi"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+		},
+	}
+	want := map[string]string{"name": "gigi"}
+
+	var count int
+	for event, err := range gobStore.FilterIter(ctx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		payload := make(map[string]string)
+		if err = event.UnmarshalPayload(&payload); err != nil {
+			t.Fatalf("unable to unmarshal payload of sequence %d: %v", event.Sequence(), err)
+		}
+		if !reflect.DeepEqual(payload, want) {
+			t.Errorf("unexpected payload want: %v, got: %v", want, payload)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("expected 2 events, got: %d", count)
+	}
+
+	for event, err := range store.FilterIter(ctx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		err = event.UnmarshalPayload(&map[string]string{})
+		if wantErr := event.Sequence() == 2; errors.Is(err, eventstore.ErrUnknownCodec) != wantErr {
+			t.Errorf("unexpected error of sequence %d: %v", event.Sequence(), err)
+		}
+	}
+}
+
+func Test_event_UnmarshalPayload(t *testing.T) {
+	gobPayload, err := gobCodec{}.Marshal(map[string]string{"name": "gigi"})
+	if err != nil {
+		t.Fatalf("unable to marshal payload: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		event   *event
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			name: "json",
+			event: &event{
+				payload:   []byte(`{"name": "gigi"}`),
+				codecName: eventstore.JSONCodec.Name(),
+				codec:     eventstore.JSONCodec,
+			},
+			want: map[string]string{"name": "gigi"},
+		},
+		{
+			name: "raw payload",
+			event: &event{
+				rawPayload: gobPayload,
+				codecName:  gobCodec{}.Name(),
+				codec:      gobCodec{},
+			},
+			want: map[string]string{"name": "gigi"},
+		},
+		{
+			name: "unknown codec",
+			event: &event{
+				rawPayload: gobPayload,
+				codecName:  gobCodec{}.Name(),
+			},
+			want:    map[string]string{},
+			wantErr: eventstore.ErrUnknownCodec,
+		},
+		{
+			name: "compressed",
+			event: &event{
+				rawPayload:  mustCompress(t, []byte(`{"name":"gigi"}`)),
+				compression: gzipCompression,
+				codecName:   eventstore.JSONCodec.Name(),
+				codec:       eventstore.JSONCodec,
+			},
+			want: map[string]string{"name": "gigi"},
+		},
+		{
+			name: "empty payload",
+			event: &event{
+				codecName: gobCodec{}.Name(),
+			},
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			if err := tt.event.UnmarshalPayload(&got); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error want: %v, got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected payload want: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_event_RawPayload(t *testing.T) {
+	payload := []byte(`{"name":"gigi"}`)
+	tests := []struct {
+		name  string
+		event *event
+		want  []byte
+	}{
+		{
+			name:  "json",
+			event: &event{payload: payload, codecName: eventstore.JSONCodec.Name()},
+			want:  payload,
+		},
+		{
+			name:  "raw payload",
+			event: &event{rawPayload: []byte("binary"), codecName: gobCodec{}.Name()},
+			want:  []byte("binary"),
+		},
+		{
+			name: "compressed",
+			event: &event{
+				rawPayload:  mustCompress(t, payload),
+				compression: gzipCompression,
+				codecName:   eventstore.JSONCodec.Name(),
+			},
+			want: payload,
+		},
+		{
+			name:  "empty",
+			event: &event{codecName: eventstore.JSONCodec.Name()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, codec, err := eventstore.RawPayload(tt.event)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("unexpected payload want: %q, got: %q", tt.want, got)
+			}
+			if codec != tt.event.codecName {
+				t.Errorf("unexpected codec want: %q, got: %q", tt.event.codecName, codec)
+			}
+		})
+	}
+}
+
+func mustCompress(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	compressed, err := compress(payload)
+	if err != nil {
+		t.Fatalf("unable to compress: %v", err)
+	}
+	return compressed
+}
@@ -9,17 +9,44 @@ import (
 
 type command struct {
 	eventstore.Command
-	payload   []byte
-	aggregate eventstore.TextSubjects
+	payload []byte
+	// codec is the [eventstore.Codec.Name] of the payload
+	codec    string
+	metadata []byte
+	// compressedPayload is the gzip compressed payload
+	// it's nil if the payload is not compressed
+	compressedPayload []byte
+	aggregate         eventstore.TextSubjects
+	// tenant is the [eventstore.TenantOf] the aggregate
+	tenant string
 
-	id       string
-	sequence uint32
+	id             string
+	sequence       uint32
+	idempotencyKey string
+
+	uniqueConstraints []*eventstore.UniqueConstraint
+
+	dataSubject      string
+	dataKeyID        string
+	encryptedPayload []byte
+
+	payloadHash []byte
+	// payloadSalt is the key of the payload hash of unencrypted payloads
+	payloadSalt  []byte
+	hash         []byte
+	previousHash []byte
+
+	keyID     string
+	signature []byte
+
+	// payloadRef is the [eventstore.BlobKey] of the offloaded payload
+	payloadRef string
 }
 
-func commandsFromAggregates(ctx context.Context, aggregates []eventstore.Aggregate) (commands []*command, close func(), err error) {
+func (store *CockroachDB) commandsFromAggregates(ctx context.Context, aggregates []eventstore.Aggregate) (commands []*command, close func(), err error) {
 	commands = make([]*command, 0, len(aggregates))
 	for _, aggregate := range aggregates {
-		aggregateEvents, err := commandsFromAggregate(ctx, aggregate)
+		aggregateEvents, err := store.commandsFromAggregate(ctx, aggregate)
 		if err != nil {
 			return nil, func() {}, err
 		}
@@ -30,25 +57,76 @@ func commandsFromAggregates(ctx context.Context, aggregates []eventstore.Aggrega
 		func() {
 			for _, cmd := range commands {
 				cmd.payload = nil
+				cmd.codec = ""
+				cmd.tenant = ""
+				cmd.compressedPayload = nil
+				cmd.metadata = nil
+				cmd.idempotencyKey = ""
+				cmd.uniqueConstraints = nil
+				cmd.dataSubject = ""
+				cmd.dataKeyID = ""
+				cmd.encryptedPayload = nil
+				cmd.payloadHash = nil
+				cmd.payloadSalt = nil
+				cmd.hash = nil
+				cmd.previousHash = nil
+				cmd.keyID = ""
+				cmd.signature = nil
+				cmd.payloadRef = ""
 				commandPool.Put(cmd)
 			}
 		},
 		nil
 }
 
-func commandsFromAggregate(ctx context.Context, aggregate eventstore.Aggregate) ([]*command, error) {
+func (store *CockroachDB) commandsFromAggregate(ctx context.Context, aggregate eventstore.Aggregate) ([]*command, error) {
+	tenant := eventstore.TenantOf(ctx, aggregate)
 	commands := make([]*command, len(aggregate.Commands()))
 	for i, command := range aggregate.Commands() {
 		commands[i] = commandPool.Get()
 
 		commands[i].Command = command
 		commands[i].aggregate = aggregate.ID()
+		commands[i].tenant = tenant
+		commands[i].codec = store.codec.Name()
+
+		if err := store.encodePayload(ctx, commands[i]); err != nil {
+			return nil, err
+		}
+
+		if store.compressionThreshold > 0 && len(commands[i].payload) >= store.compressionThreshold {
+			compressed, err := compress(commands[i].payload)
+			if err != nil {
+				logger.ErrorContext(ctx, "compress payload failed", "cause", err, "action", commands[i].Action().Join("."))
+				return nil, err
+			}
+			// compression is skipped if it doesn't reduce the size
+			if len(compressed) < len(commands[i].payload) {
+				commands[i].compressedPayload = compressed
+			}
+		}
+
+		if idempotent, ok := command.(eventstore.IdempotentCommand); ok {
+			commands[i].idempotencyKey = idempotent.IdempotencyKey()
+		}
+
+		if constraints, ok := command.(eventstore.UniqueConstraintCommand); ok {
+			commands[i].uniqueConstraints = constraints.UniqueConstraints()
+		}
+
+		if subject, ok := command.(eventstore.DataSubjectCommand); ok && len(commands[i].payload) > 0 {
+			commands[i].dataSubject = subject.DataSubject()
+		}
 
-		if command.Payload() != nil {
+		var metadata *eventstore.Metadata
+		if withMetadata, ok := command.(eventstore.CommandMetadata); ok {
+			metadata = withMetadata.Metadata()
+		}
+		if metadata = eventstore.StampMetadata(ctx, metadata); metadata != nil {
 			var err error
-			commands[i].payload, err = json.Marshal(command.Payload())
+			commands[i].metadata, err = json.Marshal(metadata)
 			if err != nil {
-				logger.ErrorContext(ctx, "marshal payload failed", "cause", err, "action", commands[i].Action().Join("."))
+				logger.ErrorContext(ctx, "marshal metadata failed", "cause", err, "action", commands[i].Action().Join("."))
 				return nil, err
 			}
 		}
@@ -56,3 +134,39 @@ func commandsFromAggregate(ctx context.Context, aggregate eventstore.Aggregate)
 
 	return commands, nil
 }
+
+// encodePayload sets the encoded payload of the command
+// pre-encoded payloads are stored as is
+func (store *CockroachDB) encodePayload(ctx context.Context, cmd *command) (err error) {
+	if raw, ok := cmd.Command.(eventstore.RawPayloadCommand); ok {
+		var codec string
+		if cmd.payload, codec = raw.RawPayload(); codec != "" {
+			cmd.codec = codec
+		}
+		return nil
+	}
+
+	switch payload := cmd.Payload().(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		if isJSON(cmd.codec) {
+			cmd.payload = payload
+			return nil
+		}
+	}
+
+	if cmd.payload, err = store.codec.Marshal(cmd.Payload()); err != nil {
+		logger.ErrorContext(ctx, "marshal payload failed", "cause", err, "action", cmd.Action().Join("."))
+		return err
+	}
+	return nil
+}
+
+// storedPayload returns the payload as it's stored, compressed payloads are stored compressed
+func (cmd *command) storedPayload() []byte {
+	if cmd.compressedPayload != nil {
+		return cmd.compressedPayload
+	}
+	return cmd.payload
+}
@@ -0,0 +1,104 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+type testRawPayloadCommand struct {
+	*testCommand
+	payload []byte
+	codec   string
+}
+
+// RawPayload implements [eventstore.RawPayloadCommand]
+func (c *testRawPayloadCommand) RawPayload() ([]byte, string) {
+	return c.payload, c.codec
+}
+
+func Test_encodePayload(t *testing.T) {
+	action := &testAction{action: eventstore.TextSubjects{"user", "1", "added"}, revision: 1}
+	tests := []struct {
+		name      string
+		store     *CockroachDB
+		command   eventstore.Command
+		want      []byte
+		wantCodec string
+	}{
+		{
+			name:      "marshalled",
+			store:     New(&Config{}),
+			command:   &testCommand{testAction: action, payload: map[string]string{"name": "gigi"}},
+			want:      []byte(`{"name":"gigi"}`),
+			wantCodec: eventstore.JSONCodec.Name(),
+		},
+		{
+			name:      "no payload",
+			store:     New(&Config{}),
+			command:   &testCommand{testAction: action},
+			wantCodec: eventstore.JSONCodec.Name(),
+		},
+		{
+			name:      "json raw message",
+			store:     New(&Config{}),
+			command:   &testCommand{testAction: action, payload: json.RawMessage(`{ "name": "gigi" }`)},
+			want:      []byte(`{ "name": "gigi" }`),
+			wantCodec: eventstore.JSONCodec.Name(),
+		},
+		{
+			name:      "json raw message with other codec",
+			store:     New(&Config{}, WithCodec(gobCodec{})),
+			command:   &testCommand{testAction: action, payload: json.RawMessage(`{}`)},
+			want:      mustMarshal(t, gobCodec{}, json.RawMessage(`{}`)),
+			wantCodec: gobCodec{}.Name(),
+		},
+		{
+			name:  "raw payload command",
+			store: New(&Config{}),
+			command: &testRawPayloadCommand{
+				testCommand: &testCommand{testAction: action, payload: "ignored"},
+				payload:     []byte("binary"),
+				codec:       gobCodec{}.Name(),
+			},
+			want:      []byte("binary"),
+			wantCodec: gobCodec{}.Name(),
+		},
+		{
+			name:  "raw payload command of store codec",
+			store: New(&Config{}),
+			command: &testRawPayloadCommand{
+				testCommand: &testCommand{testAction: action},
+				payload:     []byte(`{"name":"gigi"}`),
+			},
+			want:      []byte(`{"name":"gigi"}`),
+			wantCodec: eventstore.JSONCodec.Name(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &command{Command: tt.command, codec: tt.store.codec.Name()}
+			if err := tt.store.encodePayload(context.Background(), cmd); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(cmd.payload, tt.want) {
+				t.Errorf("unexpected payload want: %q, got: %q", tt.want, cmd.payload)
+			}
+			if cmd.codec != tt.wantCodec {
+				t.Errorf("unexpected codec want: %q, got: %q", tt.wantCodec, cmd.codec)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, codec eventstore.Codec, object any) []byte {
+	t.Helper()
+	data, err := codec.Marshal(object)
+	if err != nil {
+		t.Fatalf("unable to marshal: %v", err)
+	}
+	return data
+}
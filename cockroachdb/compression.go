@@ -0,0 +1,58 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// gzipCompression is stored in the compression column of gzip compressed payloads
+const gzipCompression = "gzip"
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// compress returns the gzip compressed payload
+func compress(payload []byte) ([]byte, error) {
+	writer := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(writer)
+
+	var compressed bytes.Buffer
+	writer.Reset(&compressed)
+	if _, err := writer.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// decompress reverts the compression of the payload
+// payloads without compression are returned as is
+func decompress(compression string, payload []byte) ([]byte, error) {
+	switch compression {
+	case "":
+		return payload, nil
+	case gzipCompression:
+		reader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	default:
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+}
+
+// compression returns the value of the compression column of the command
+func (cmd *command) compression() string {
+	if cmd.compressedPayload == nil {
+		return ""
+	}
+	return gzipCompression
+}
@@ -0,0 +1,163 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// largeDocument returns a multi kilobyte payload
+func largeDocument() map[string]string {
+	document := make(map[string]string, 64)
+	for i := range 64 {
+		document["paragraph"+strconv.Itoa(i)] = strings.Repeat("lorem ipsum dolor sit amet ", 4)
+	}
+	return document
+}
+
+func largeDocumentAggregate(id string) *testAggregate {
+	return &testAggregate{
+		id: eventstore.TextSubjects{"document", eventstore.TextSubject(id)},
+		commands: []eventstore.Command{
+			&testCommand{
+				testAction: &testAction{action: eventstore.TextSubjects{"document", eventstore.TextSubject(id), "added"}, revision: 1},
+				payload:    largeDocument(),
+			},
+		},
+	}
+}
+
+// compressionBenchStores are the stores compared by the benchmarks of large payloads
+func compressionBenchStores() []struct {
+	name  string
+	store *CockroachDB
+} {
+	return []struct {
+		name  string
+		store *CockroachDB
+	}{
+		{name: "uncompressed", store: store.CockroachDB},
+		{name: "compressed", store: New(&Config{Pool: store.client}, WithCompression(1024))},
+	}
+}
+
+// reportStoredPayloadSize reports the average size of the stored payloads
+func reportStoredPayloadSize(ctx context.Context, b *testing.B) {
+	b.Helper()
+	var size float64
+	err := store.client.QueryRow(ctx, `SELECT COALESCE(avg(COALESCE(octet_length(raw_payload), octet_length(payload::STRING))), 0)::FLOAT8 FROM eventstore.events`).Scan(&size)
+	if err != nil {
+		b.Fatalf("unable to query payload size: %v", err)
+	}
+	b.ReportMetric(size, "payload-bytes/event")
+}
+
+func Test_Compression(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	compressing := New(&Config{Pool: store.client}, WithCompression(1024))
+
+	if err := compressing.Push(ctx, largeDocumentAggregate("1")); err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	var compression string
+	if err := store.client.QueryRow(ctx, `SELECT compression FROM eventstore.events`).Scan(&compression); err != nil {
+		t.Fatalf("unable to query compression: %v", err)
+	}
+	if compression != gzipCompression {
+		t.Errorf("expected compressed payload, got: %q", compression)
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("document"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+		},
+	}
+	var found bool
+	for event, err := range store.FilterIter(ctx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		payload := make(map[string]string)
+		if err = event.UnmarshalPayload(&payload); err != nil {
+			t.Fatalf("unable to unmarshal payload: %v", err)
+		}
+		if !reflect.DeepEqual(payload, largeDocument()) {
+			t.Errorf("unexpected payload: %v", payload)
+		}
+		found = true
+	}
+	if !found {
+		t.Error("compressed event must be stored")
+	}
+}
+
+func Test_compress(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"name":"gigi"}`, 100))
+	compressed, err := compress(payload)
+	if err != nil {
+		t.Fatalf("unable to compress: %v", err)
+	}
+	if len(compressed) >= len(payload) {
+		t.Errorf("expected compressed payload to be smaller, got %d bytes for %d", len(compressed), len(payload))
+	}
+
+	tests := []struct {
+		name        string
+		compression string
+		payload     []byte
+		want        []byte
+		wantErr     bool
+	}{
+		{
+			name:        "gzip",
+			compression: gzipCompression,
+			payload:     compressed,
+			want:        payload,
+		},
+		{
+			name:    "uncompressed",
+			payload: payload,
+			want:    payload,
+		},
+		{
+			name:        "unknown",
+			compression: "zip",
+			payload:     compressed,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decompress(tt.compression, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("unexpected payload want: %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Benchmark_compress(b *testing.B) {
+	payload, err := eventstore.JSONCodec.Marshal(largeDocument())
+	if err != nil {
+		b.Fatalf("unable to marshal payload: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err = compress(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
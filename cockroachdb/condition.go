@@ -0,0 +1,73 @@
+package cockroachdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var (
+	// the position is compared as decimal to keep the logical part of the timestamp
+	appendConditionPrefix = `SELECT EXISTS (SELECT 1 FROM {{schema}}.events e WHERE e."position" > $1::DECIMAL`
+	appendConditionSuffix = `)`
+)
+
+// appendCondition is the condition of an aggregate
+// it's checked against the events of the tenant of the aggregate
+type appendCondition struct {
+	*eventstore.AppendCondition
+	tenant string
+}
+
+// appendConditions returns [eventstore.ErrAppendConditionFailed]
+// if an event matching a condition was stored after the position of the condition
+func (store *CockroachDB) appendConditions(ctx context.Context, tx pgx.Tx, conditions []*appendCondition) error {
+	for _, condition := range conditions {
+		stmt, args := appendConditionStatement(condition.AppendCondition, condition.tenant)
+
+		var exists bool
+		if err := tx.QueryRow(ctx, store.qualify(stmt), args...).Scan(&exists); err != nil {
+			logger.ErrorContext(ctx, "check append condition failed", "cause", err)
+			return err
+		}
+		if exists {
+			logger.DebugContext(ctx, "append condition failed", "after", condition.After)
+			return eventstore.ErrAppendConditionFailed
+		}
+	}
+	return nil
+}
+
+func appendConditionStatement(condition *eventstore.AppendCondition, tenant string) (string, []any) {
+	var (
+		builder strings.Builder
+		index   = 1
+		args    = []any{positionArg(condition.After)}
+	)
+
+	builder.WriteString(appendConditionPrefix)
+	if condition.Filter == nil || !condition.Filter.CrossTenant {
+		builder.WriteString(" AND ")
+		args = append(args, tenantClause(&builder, &index, tenant)...)
+	}
+	if condition.Filter != nil && len(condition.Filter.Queries) > 0 {
+		builder.WriteString(" AND (")
+		args = append(args, queriesToClause(&builder, &index, condition.Filter.Queries)...)
+		builder.WriteRune(')')
+	}
+	builder.WriteString(appendConditionSuffix)
+
+	return builder.String(), args
+}
+
+// positionArg returns the position as decimal argument,
+// an empty position is before all events
+func positionArg(position eventstore.Position) string {
+	if position.IsZero() {
+		return "0"
+	}
+	return position.String()
+}
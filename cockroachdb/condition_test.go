@@ -0,0 +1,93 @@
+package cockroachdb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+func Test_AppendCondition_Compliance(t *testing.T) {
+	eventstore.AppendConditionComplianceTests(context.Background(), t, store)
+}
+
+func Test_appendConditionStatement(t *testing.T) {
+	type want struct {
+		stmt string
+		args []any
+	}
+	tests := []struct {
+		name      string
+		condition *eventstore.AppendCondition
+		tenant    string
+		want      want
+	}{
+		{
+			name: "no filter",
+			condition: &eventstore.AppendCondition{
+				After: "1760890000000000000.0000000001",
+			},
+			want: want{
+				stmt: `SELECT EXISTS (SELECT 1 FROM {{schema}}.events e WHERE e."position" > $1::DECIMAL AND e.tenant = $2)`,
+				args: []any{"1760890000000000000.0000000001", ""},
+			},
+		},
+		{
+			name: "multiple queries",
+			condition: &eventstore.AppendCondition{
+				Filter: &eventstore.Filter{
+					Queries: []*eventstore.FilterQuery{
+						{
+							Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.MultiToken},
+						},
+						{
+							Subjects: []eventstore.Subject{eventstore.SingleToken},
+						},
+					},
+				},
+				After: "2",
+			},
+			tenant: "tenant",
+			want: want{
+				stmt: `SELECT EXISTS (SELECT 1 FROM {{schema}}.events e WHERE e."position" > $1::DECIMAL AND e.tenant = $2 AND ((e.id IN (SELECT a.event FROM {{schema}}.actions a WHERE a.action = $3 AND a.depth = $4) AND e.action_depth >= $5) OR (e.action_depth = $6)))`,
+				args: []any{
+					"2",
+					"tenant",
+					eventstore.TextSubject("user"), 0, 2,
+					1,
+				},
+			},
+		},
+		{
+			name: "cross tenant",
+			condition: &eventstore.AppendCondition{
+				Filter: &eventstore.Filter{
+					Queries: []*eventstore.FilterQuery{
+						{
+							Subjects: []eventstore.Subject{eventstore.SingleToken},
+						},
+					},
+					CrossTenant: true,
+				},
+				After: "3",
+			},
+			tenant: "tenant",
+			want: want{
+				stmt: `SELECT EXISTS (SELECT 1 FROM {{schema}}.events e WHERE e."position" > $1::DECIMAL AND ((e.action_depth = $2)))`,
+				args: []any{"3", 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, args := appendConditionStatement(tt.condition, tt.tenant)
+			if stmt != tt.want.stmt {
+				t.Errorf("unexpected stmt want:\n%q\ngot:\n%q", tt.want.stmt, stmt)
+			}
+			if !reflect.DeepEqual(args, tt.want.args) {
+				t.Errorf("unexpected args want:\n%v\ngot:\n%v", tt.want.args, args)
+			}
+		})
+	}
+}
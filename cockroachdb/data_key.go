@@ -0,0 +1,160 @@
+package cockroachdb
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var _ eventstore.Forgetter = (*CockroachDB)(nil)
+
+var (
+	createDataKeysStmt = `INSERT INTO {{schema}}.data_keys (tenant, subject, "key") SELECT * FROM unnest($1::TEXT[], $2::TEXT[], $3::BYTES[]) ON CONFLICT (tenant, subject) DO NOTHING`
+	selectDataKeysStmt = `SELECT tenant, subject, id, "key" FROM {{schema}}.data_keys WHERE (tenant, subject) IN (SELECT * FROM unnest($1::TEXT[], $2::TEXT[]))`
+	forgetSubjectStmt  = `DELETE FROM {{schema}}.data_keys WHERE tenant = $1 AND subject = $2`
+)
+
+// dataKeySize is the size of the AES-256 keys of the data subjects
+const dataKeySize = 32
+
+var errInvalidCiphertext = errors.New("invalid ciphertext")
+
+type dataKey struct {
+	id  string
+	key []byte
+}
+
+// ForgetSubject implements [eventstore.Forgetter]
+// The key of the subject of [eventstore.TenantFromContext] is deleted, the encrypted payloads are kept.
+// A subsequent push of the subject creates a new key.
+func (store *CockroachDB) ForgetSubject(ctx context.Context, subject string) error {
+	if _, err := store.client.Exec(ctx, store.qualify(forgetSubjectStmt), eventstore.TenantFromContext(ctx), subject); err != nil {
+		logger.ErrorContext(ctx, "forget subject failed", "cause", err)
+		return err
+	}
+	return nil
+}
+
+// encryptPayloads encrypts the payloads of the commands with the key of their data subject
+// the keys of subjects without a key are created
+func (store *CockroachDB) encryptPayloads(ctx context.Context, tx pgx.Tx, commands []*command) error {
+	subjects := make([]tenantKey, 0, len(commands))
+	for _, cmd := range commands {
+		subject := tenantKey{tenant: cmd.tenant, key: cmd.dataSubject}
+		if cmd.dataSubject == "" || slices.Contains(subjects, subject) {
+			continue
+		}
+		subjects = append(subjects, subject)
+	}
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	keys, err := store.dataKeys(ctx, tx, subjects)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range commands {
+		if cmd.dataSubject == "" {
+			continue
+		}
+		key := keys[tenantKey{tenant: cmd.tenant, key: cmd.dataSubject}]
+		cmd.dataKeyID = key.id
+		if cmd.encryptedPayload, err = encrypt(key.key, cmd.storedPayload(), cmd.dataSubject); err != nil {
+			logger.ErrorContext(ctx, "encrypt payload failed", "cause", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dataKeys returns the keys of the subjects of each tenant, missing keys are created
+func (store *CockroachDB) dataKeys(ctx context.Context, tx pgx.Tx, subjects []tenantKey) (map[tenantKey]*dataKey, error) {
+	var (
+		tenants      = make([]string, len(subjects))
+		subjectNames = make([]string, len(subjects))
+		newKeys      = make([][]byte, len(subjects))
+	)
+	for i, subject := range subjects {
+		tenants[i], subjectNames[i] = subject.tenant, subject.key
+		newKeys[i] = make([]byte, dataKeySize)
+		if _, err := rand.Read(newKeys[i]); err != nil {
+			logger.ErrorContext(ctx, "generate data key failed", "cause", err)
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx, store.qualify(createDataKeysStmt), tenants, subjectNames, newKeys); err != nil {
+		logger.ErrorContext(ctx, "create data keys failed", "cause", err)
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, store.qualify(selectDataKeysStmt), tenants, subjectNames)
+	if err != nil {
+		logger.ErrorContext(ctx, "query data keys failed", "cause", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[tenantKey]*dataKey, len(subjects))
+	for rows.Next() {
+		var (
+			subject tenantKey
+			key     = new(dataKey)
+		)
+		if err = rows.Scan(&subject.tenant, &subject.key, &key.id, &key.key); err != nil {
+			logger.ErrorContext(ctx, "scan of data keys failed", "cause", err)
+			return nil, err
+		}
+		keys[subject] = key
+	}
+	if err = rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "read data keys failed", "cause", err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// encrypt seals the plaintext using AES-GCM, the nonce is prepended to the ciphertext
+// the subject is authenticated to prevent payloads from being moved between subjects
+func encrypt(key, plaintext []byte, subject string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(subject)), nil
+}
+
+// decrypt opens the ciphertext created by [encrypt]
+func decrypt(key, ciphertext []byte, subject string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errInvalidCiphertext
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(subject))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,74 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+func Test_CryptoShredding_Compliance(t *testing.T) {
+	eventstore.CryptoShreddingComplianceTests(context.Background(), t, store)
+}
+
+func Test_encrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, dataKeySize)
+	otherKey := bytes.Repeat([]byte{2}, dataKeySize)
+	plaintext := []byte(`{"firstName":"first name"}`)
+
+	ciphertext, err := encrypt(key, plaintext, "user")
+	if err != nil {
+		t.Fatalf("unexpected error on encrypt: %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("ciphertext must not contain the plaintext")
+	}
+
+	tests := []struct {
+		name       string
+		key        []byte
+		ciphertext []byte
+		subject    string
+		wantErr    bool
+	}{
+		{
+			name:       "valid",
+			key:        key,
+			ciphertext: ciphertext,
+			subject:    "user",
+		},
+		{
+			name:       "other key",
+			key:        otherKey,
+			ciphertext: ciphertext,
+			subject:    "user",
+			wantErr:    true,
+		},
+		{
+			name:       "other subject",
+			key:        key,
+			ciphertext: ciphertext,
+			subject:    "other",
+			wantErr:    true,
+		},
+		{
+			name:       "too short",
+			key:        key,
+			ciphertext: ciphertext[:4],
+			subject:    "user",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decrypt(tt.key, tt.ciphertext, tt.subject)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, plaintext) {
+				t.Errorf("decrypt() = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package cockroachdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var (
+	deduplicateStmt   = `SELECT tenant, idempotency_key, "sequence", created_at FROM {{schema}}.events WHERE (tenant, idempotency_key) IN (SELECT * FROM unnest($1::TEXT[], $2::TEXT[]))`
+	deduplicateWindow = ` AND created_at > now() - $3::INTERVAL`
+)
+
+// tenantKey scopes an idempotency key or a data subject to its tenant
+type tenantKey struct {
+	tenant string
+	key    string
+}
+
+type storedCommand struct {
+	sequence     uint32
+	creationDate time.Time
+}
+
+// deduplicate removes the commands whose idempotency key was already stored
+// the returned commands must be pushed
+func (store *CockroachDB) deduplicate(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes, commands []*command) (_ []*command, err error) {
+	var (
+		tenants = make([]string, 0, len(commands))
+		keys    = make([]string, 0, len(commands))
+	)
+	for _, cmd := range commands {
+		if cmd.idempotencyKey != "" {
+			tenants = append(tenants, cmd.tenant)
+			keys = append(keys, cmd.idempotencyKey)
+		}
+	}
+	if len(keys) == 0 {
+		return commands, nil
+	}
+
+	stmt, args := deduplicateStmt, []any{tenants, keys}
+	if store.deduplicationWindow > 0 {
+		stmt += deduplicateWindow
+		args = append(args, store.deduplicationWindow)
+	}
+
+	rows, err := tx.Query(ctx, store.qualify(stmt), args...)
+	if err != nil {
+		logger.ErrorContext(ctx, "query idempotency keys failed", "cause", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[tenantKey]*storedCommand, len(keys))
+	for rows.Next() {
+		var (
+			key     tenantKey
+			command = new(storedCommand)
+		)
+		if err = rows.Scan(&key.tenant, &key.key, &command.sequence, &command.creationDate); err != nil {
+			logger.ErrorContext(ctx, "scan of idempotency keys failed", "cause", err)
+			return nil, err
+		}
+		stored[key] = command
+	}
+	if err = rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "read idempotency keys failed", "cause", err)
+		return nil, err
+	}
+
+	var (
+		pending    = make([]*command, 0, len(commands))
+		duplicates = make([]*command, 0, len(keys))
+		// seen prevents duplicates inside the same push
+		seen = make(map[tenantKey]struct{}, len(keys))
+	)
+	for _, cmd := range commands {
+		if cmd.idempotencyKey == "" {
+			pending = append(pending, cmd)
+			continue
+		}
+		key := tenantKey{tenant: cmd.tenant, key: cmd.idempotencyKey}
+		_, isSeen := seen[key]
+		if _, isStored := stored[key]; !isStored && !isSeen {
+			seen[key] = struct{}{}
+			pending = append(pending, cmd)
+			continue
+		}
+		duplicates = append(duplicates, cmd)
+	}
+
+	if len(duplicates) == 0 {
+		return commands, nil
+	}
+
+	if store.rejectDuplicates {
+		duplicateKeys := make([]string, len(duplicates))
+		for i, duplicate := range duplicates {
+			duplicateKeys[i] = duplicate.idempotencyKey
+		}
+		logger.DebugContext(ctx, "duplicate commands", "keys", duplicateKeys)
+		return nil, fmt.Errorf("%w: %s", eventstore.ErrDuplicateCommand, strings.Join(duplicateKeys, ", "))
+	}
+
+	for _, duplicate := range duplicates {
+		if original, ok := stored[tenantKey{tenant: duplicate.tenant, key: duplicate.idempotencyKey}]; ok {
+			duplicate.SetSequence(original.sequence)
+			duplicate.SetCreationDate(original.creationDate)
+		}
+		duplicate.Command.(eventstore.IdempotentCommand).SetDeduplicated()
+	}
+	indexes.skipDeduplicated(commands, pending)
+
+	return pending, nil
+}
@@ -1,22 +1,119 @@
 package cockroachdb
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/adlerhurst/eventstore/v2"
 )
 
-var _ eventstore.Event = (*event)(nil)
+var (
+	_ eventstore.Event           = (*event)(nil)
+	_ eventstore.RedactedEvent   = (*event)(nil)
+	_ eventstore.SignedEvent     = (*event)(nil)
+	_ eventstore.RawPayloadEvent = (*event)(nil)
+	_ eventstore.CloneableEvent  = (*event)(nil)
+	_ eventstore.TenantEvent     = (*event)(nil)
+)
 
 type event struct {
+	id           string
+	tenant       string
 	action       eventstore.TextSubjects
 	aggregate    eventstore.TextSubjects
 	revision     uint16
 	creationDate time.Time
-	position     float64
+	position     eventstore.Position
 	sequence     uint32
 	payload      []byte
+	metadata     *eventstore.Metadata
+
+	// codecName is the [eventstore.Codec.Name] of the payload
+	// codec is nil if the store doesn't know the codec
+	codecName  string
+	codec      eventstore.Codec
+	rawPayload []byte
+	// compression of the stored payload, empty if not compressed
+	compression string
+	// payloadRef is the [eventstore.BlobKey] of the offloaded payload
+	// the payload is fetched lazily from blobs using the values of the ctx of the filter,
+	// the ctx is not canceled with the filter because events can be retained or cloned
+	payloadRef string
+	blobs      eventstore.BlobStore
+	ctx        context.Context
+
+	dataSubject      string
+	encryptedPayload []byte
+	dataKey          []byte
+
+	redactedAt *time.Time
+
+	keyID     string
+	signature []byte
+}
+
+// reset clears the references of the event before it's put back to the pool
+func (e *event) reset() {
+	e.payload = nil
+	e.codec = nil
+	e.rawPayload = nil
+	e.blobs = nil
+	e.ctx = nil
+	e.metadata = nil
+	e.encryptedPayload = nil
+	e.dataKey = nil
+	e.redactedAt = nil
+	e.signature = nil
+}
+
+// newEvent returns an event to scan into
+// events are taken from the pool unless they are retained
+func (store *CockroachDB) newEvent() *event {
+	if store.retainEvents {
+		return new(event)
+	}
+	return eventPool.Get()
+}
+
+// releaseEvent puts the event back to the pool unless events are retained
+func (store *CockroachDB) releaseEvent(e *event) {
+	if store.retainEvents {
+		return
+	}
+	e.reset()
+	eventPool.Put(e)
+}
+
+// Clone implements [eventstore.CloneableEvent]
+// offloaded payloads are still fetched using the context of the filter
+func (e *event) Clone() eventstore.Event {
+	clone := *e
+	clone.action = slices.Clone(e.action)
+	clone.aggregate = slices.Clone(e.aggregate)
+	clone.payload = bytes.Clone(e.payload)
+	clone.rawPayload = bytes.Clone(e.rawPayload)
+	clone.encryptedPayload = bytes.Clone(e.encryptedPayload)
+	clone.dataKey = bytes.Clone(e.dataKey)
+	clone.signature = bytes.Clone(e.signature)
+	if e.metadata != nil {
+		metadata := *e.metadata
+		metadata.Headers = maps.Clone(e.metadata.Headers)
+		clone.metadata = &metadata
+	}
+	if e.redactedAt != nil {
+		redactedAt := *e.redactedAt
+		clone.redactedAt = &redactedAt
+	}
+	return &clone
+}
+
+// ID implements [eventstore.Event]
+func (e *event) ID() string {
+	return e.id
 }
 
 // Action implements [eventstore.Event]
@@ -29,6 +126,11 @@ func (e *event) Aggregate() eventstore.TextSubjects {
 	return e.aggregate
 }
 
+// Tenant implements [eventstore.TenantEvent]
+func (e *event) Tenant() string {
+	return e.tenant
+}
+
 // Revision implements [eventstore.Event]
 func (e *event) Revision() uint16 {
 	return e.revision
@@ -44,10 +146,78 @@ func (e *event) Sequence() uint32 {
 	return e.sequence
 }
 
+// Metadata implements [eventstore.Event]
+func (e *event) Metadata() *eventstore.Metadata {
+	return e.metadata
+}
+
+// Position implements [eventstore.Event]
+func (e *event) Position() eventstore.Position {
+	return e.position
+}
+
+// RedactedAt implements [eventstore.RedactedEvent]
+func (e *event) RedactedAt() time.Time {
+	if e.redactedAt == nil {
+		return time.Time{}
+	}
+	return *e.redactedAt
+}
+
 // UnmarshalPayload implements [eventstore.Event]
+// The payload is decoded by the codec it was encoded with.
+// If the data subject of the payload was forgotten [eventstore.ForgottenError] is returned
 func (e *event) UnmarshalPayload(object any) error {
-	if len(e.payload) == 0 {
-		return nil
+	payload, err := e.plainPayload()
+	if err != nil || len(payload) == 0 {
+		return err
+	}
+	if e.codec == nil {
+		return fmt.Errorf("%w: %q", eventstore.ErrUnknownCodec, e.codecName)
+	}
+	return e.codec.Unmarshal(payload, object)
+}
+
+// RawPayload implements [eventstore.RawPayloadEvent]
+// offloaded, encrypted and compressed payloads are returned as encoded by the codec
+// If the data subject of the payload was forgotten [eventstore.ForgottenError] is returned
+func (e *event) RawPayload() ([]byte, error) {
+	payload, err := e.plainPayload()
+	if err != nil || len(payload) == 0 {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// PayloadCodec implements [eventstore.RawPayloadEvent]
+func (e *event) PayloadCodec() string {
+	return e.codecName
+}
+
+// plainPayload returns the encoded payload
+// offloaded payloads are fetched, encrypted payloads are decrypted and compressed payloads are decompressed
+func (e *event) plainPayload() (payload []byte, err error) {
+	if e.dataSubject != "" && e.dataKey == nil {
+		return nil, &eventstore.ForgottenError{Subject: e.dataSubject}
+	}
+
+	switch {
+	case e.payloadRef != "":
+		if payload, err = loadBlob(e.ctx, e.blobs, e.payloadRef); err != nil {
+			return nil, err
+		}
+	case e.dataSubject != "":
+		payload = e.encryptedPayload
+	case e.rawPayload != nil:
+		payload = e.rawPayload
+	default:
+		payload = e.payload
+	}
+
+	if e.dataSubject != "" {
+		if payload, err = decrypt(e.dataKey, payload, e.dataSubject); err != nil {
+			return nil, err
+		}
 	}
-	return json.Unmarshal(e.payload, object)
+	return decompress(e.compression, payload)
 }
@@ -0,0 +1,120 @@
+package cockroachdb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+func Test_RetainedEvents(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+
+	commands := make([]eventstore.Command, 3)
+	for i, name := range []string{"gigi", "gugu", "gaga"} {
+		commands[i] = &testCommand{
+			testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "changed"}, revision: 1},
+			payload:    map[string]string{"name": name},
+		}
+	}
+	if err := store.Push(ctx, &testAggregate{id: eventstore.TextSubjects{"user", "1"}, commands: commands}); err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+		},
+	}
+	tests := []struct {
+		name  string
+		store *CockroachDB
+		keep  func(eventstore.Event) eventstore.Event
+	}{
+		{
+			name:  "retained",
+			store: New(&Config{Pool: store.client}, WithRetainedEvents()),
+			keep:  func(event eventstore.Event) eventstore.Event { return event },
+		},
+		{
+			name:  "cloned",
+			store: store.CockroachDB,
+			keep:  eventstore.Clone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []eventstore.Event
+			err := tt.store.Filter(ctx, filter, reduceFunc(func(reduced ...eventstore.Event) error {
+				for _, event := range reduced {
+					events = append(events, tt.keep(event))
+				}
+				return nil
+			}))
+			if err != nil {
+				t.Fatalf("unable to filter events: %v", err)
+			}
+
+			for i, name := range []string{"gigi", "gugu", "gaga"} {
+				payload := make(map[string]string)
+				if err = events[i].UnmarshalPayload(&payload); err != nil {
+					t.Fatalf("unable to unmarshal payload: %v", err)
+				}
+				if payload["name"] != name || events[i].Sequence() != uint32(i+1) {
+					t.Errorf("event %d was reused: sequence %d, payload %v", i, events[i].Sequence(), payload)
+				}
+			}
+		})
+	}
+}
+
+type reduceFunc func(events ...eventstore.Event) error
+
+// Reduce implements [eventstore.Reducer]
+func (f reduceFunc) Reduce(events ...eventstore.Event) error {
+	return f(events...)
+}
+
+func Test_event_Clone(t *testing.T) {
+	redactedAt := time.Now()
+	original := &event{
+		id:           "1",
+		action:       eventstore.TextSubjects{"user", "1", "added"},
+		aggregate:    eventstore.TextSubjects{"user", "1"},
+		revision:     1,
+		sequence:     1,
+		payload:      []byte(`{"name":"gigi"}`),
+		metadata:     &eventstore.Metadata{Creator: "gigi", Headers: map[string]string{"key": "value"}},
+		codecName:    eventstore.JSONCodec.Name(),
+		codec:        eventstore.JSONCodec,
+		rawPayload:   []byte("raw"),
+		signature:    []byte("signature"),
+		redactedAt:   &redactedAt,
+		creationDate: redactedAt,
+	}
+	want := *original
+	want.action = eventstore.TextSubjects{"user", "1", "added"}
+	want.payload = []byte(`{"name":"gigi"}`)
+	want.metadata = &eventstore.Metadata{Creator: "gigi", Headers: map[string]string{"key": "value"}}
+	want.rawPayload = []byte("raw")
+	want.signature = []byte("signature")
+
+	clone := original.Clone()
+
+	// simulate the reuse of the pooled event
+	original.action[2] = "removed"
+	original.payload[2] = 'N'
+	original.metadata.Headers["key"] = "changed"
+	original.rawPayload[0] = 'R'
+	original.signature[0] = 'S'
+	original.reset()
+
+	if !reflect.DeepEqual(clone, &want) {
+		t.Errorf("clone must not be affected by the reuse of the event want:\n%+v\ngot:\n%+v", &want, clone)
+	}
+}
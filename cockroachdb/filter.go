@@ -3,16 +3,59 @@ package cockroachdb
 import (
 	"context"
 	_ "embed"
+	"iter"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/adlerhurst/eventstore/v2"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Filter implements [eventstore.Eventstore]
+// If the reducer implements [eventstore.BatchReducer] the events are reduced in batches.
+// If the reducer implements [eventstore.ContextReducer] it receives ctx.
 func (store *CockroachDB) Filter(ctx context.Context, filter *eventstore.Filter, reducer eventstore.Reducer) (err error) {
-	builder, args := store.prepareStatement(filter)
+	batch := &eventBatch{size: 1}
+	if batching, ok := reducer.(eventstore.BatchReducer); ok {
+		batch.size, batch.byTransaction = batching.BatchSize(), batching.BatchByTransaction()
+	}
+
+	var reduceErr error
+	err = store.filter(ctx, filter, batch, func(events ...eventstore.Event) bool {
+		if reduceErr = eventstore.Reduce(ctx, reducer, events...); reduceErr != nil {
+			logger.DebugContext(ctx, "reduce failed", "cause", reduceErr)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return reduceErr
+}
+
+// FilterIter implements [eventstore.FilterIterator]
+// The events are streamed from the rows cursor, the connection
+// and transaction are released as soon as the loop ends.
+// An event is only valid until the next iteration
+// unless [WithRetainedEvents] is set, use [eventstore.Clone] to keep it.
+func (store *CockroachDB) FilterIter(ctx context.Context, filter *eventstore.Filter) iter.Seq2[eventstore.Event, error] {
+	return func(yield func(eventstore.Event, error) bool) {
+		err := store.filter(ctx, filter, &eventBatch{size: 1}, func(events ...eventstore.Event) bool {
+			return yield(events[0], nil)
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// filter yields the events in batches
+// the events are released after yield returns
+func (store *CockroachDB) filter(ctx context.Context, filter *eventstore.Filter, batch *eventBatch, yield func(events ...eventstore.Event) bool) (err error) {
+	builder, args := store.prepareStatement(filter, eventstore.TenantFromContext(ctx))
 
 	conn, err := store.client.Acquire(ctx)
 	if err != nil {
@@ -29,6 +72,12 @@ func (store *CockroachDB) Filter(ctx context.Context, filter *eventstore.Filter,
 		return err
 	}
 
+	if !filter.AwaitPosition.IsZero() {
+		if err = store.awaitPosition(ctx, conn, filter.AwaitPosition); err != nil {
+			return err
+		}
+	}
+
 	tx, err := conn.BeginTx(ctx, pgx.TxOptions{
 		IsoLevel:   pgx.ReadCommitted,
 		AccessMode: pgx.ReadOnly,
@@ -46,62 +95,170 @@ func (store *CockroachDB) Filter(ctx context.Context, filter *eventstore.Filter,
 		_ = tx.Commit(ctx)
 	}()
 
-	rows, err := tx.Query(ctx, builder.String(), args...)
+	rows, err := tx.Query(ctx, store.qualify(builder.String()), args...)
 	if err != nil {
 		logger.ErrorContext(ctx, "filter events failed", "cause", err)
 		return err
 	}
 	defer rows.Close()
+	// releases the events of a batch which was not yielded
+	defer store.releaseBatch(batch)
 
+	// offloaded payloads can be fetched after the filter finished, e.g. by retained or cloned events
+	blobCtx := context.WithoutCancel(ctx)
 	for rows.Next() {
-		event := eventPool.Get()
+		event := store.newEvent()
 		err = rows.Scan(
+			&event.id,
 			&event.aggregate,
 			&event.revision,
 			&event.payload,
 			&event.sequence,
 			&event.creationDate,
 			&event.action,
+			&event.metadata,
+			&event.position,
+			&event.dataSubject,
+			&event.encryptedPayload,
+			&event.dataKey,
+			&event.redactedAt,
+			&event.keyID,
+			&event.signature,
+			&event.codecName,
+			&event.rawPayload,
+			&event.compression,
+			&event.payloadRef,
+			&event.tenant,
 		)
 		if err != nil {
 			logger.ErrorContext(ctx, "scan of events failed", "cause", err)
-			event.payload = nil
-			eventPool.Put(event)
+			store.releaseEvent(event)
 			return err
 		}
 
-		if err = reducer.Reduce(event); err != nil {
-			logger.DebugContext(ctx, "reduce failed", "cause", err)
-			event.payload = nil
-			eventPool.Put(event)
-			return err
+		event.codec = store.codecs[event.codecName]
+		event.blobs, event.ctx = store.blobs, blobCtx
+
+		if batch.endsBefore(event) && !store.yieldBatch(batch, yield) {
+			store.releaseEvent(event)
+			return nil
 		}
-		event.payload = nil
-		eventPool.Put(event)
+		batch.events = append(batch.events, event)
+		if batch.isFull() && !store.yieldBatch(batch, yield) {
+			return nil
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "read events failed", "cause", err)
+		return err
 	}
 
+	if len(batch.events) > 0 {
+		store.yieldBatch(batch, yield)
+	}
 	return nil
 }
 
+// awaitPositionStmt checks if the events of the position are visible for the [filterIgnoreOpenPush] clause
+const awaitPositionStmt = `SELECT hlc_to_timestamp($1::DECIMAL) < (SELECT COALESCE(MIN(start), NOW())::TIMESTAMPTZ FROM crdb_internal.cluster_transactions where application_name = $2)`
+
+// awaitInterval is the time between two checks of [CockroachDB.awaitPosition]
+var awaitInterval = 20 * time.Millisecond
+
+// awaitPosition blocks until all events up to the position are visible
+// or the context is done
+func (store *CockroachDB) awaitPosition(ctx context.Context, conn *pgxpool.Conn, position eventstore.Position) error {
+	ticker := time.NewTicker(awaitInterval)
+	defer ticker.Stop()
+
+	for {
+		var isVisible bool
+		if err := conn.QueryRow(ctx, awaitPositionStmt, position.String(), store.pushAppName).Scan(&isVisible); err != nil {
+			logger.ErrorContext(ctx, "check position failed", "cause", err)
+			return err
+		}
+		if isVisible {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.DebugContext(ctx, "await position canceled", "position", position, "cause", ctx.Err())
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// eventBatch collects the events which are yielded together
+type eventBatch struct {
+	events []*event
+	// reduced is reused to pass the events to the reducer
+	reduced []eventstore.Event
+
+	// size is the maximum count of events, 0 is unlimited
+	size int
+	// byTransaction ends the batch if the position of the events changes
+	byTransaction bool
+}
+
+// endsBefore checks if event belongs to the next batch
+func (batch *eventBatch) endsBefore(event *event) bool {
+	return batch.byTransaction &&
+		len(batch.events) > 0 &&
+		batch.events[len(batch.events)-1].position.Compare(event.position) != 0
+}
+
+// isFull checks if the batch reached its size
+func (batch *eventBatch) isFull() bool {
+	return batch.size > 0 && len(batch.events) >= batch.size
+}
+
+// yieldBatch yields the events of the batch and releases them afterwards
+func (store *CockroachDB) yieldBatch(batch *eventBatch, yield func(events ...eventstore.Event) bool) bool {
+	batch.reduced = batch.reduced[:0]
+	for _, event := range batch.events {
+		batch.reduced = append(batch.reduced, event)
+	}
+	next := yield(batch.reduced...)
+	store.releaseBatch(batch)
+	return next
+}
+
+// releaseBatch releases the events of the batch
+func (store *CockroachDB) releaseBatch(batch *eventBatch) {
+	for _, event := range batch.events {
+		store.releaseEvent(event)
+	}
+	batch.events = batch.events[:0]
+}
+
 var (
-	filterColumnSelector = "SELECT e.aggregate, e.revision, e.payload, e.sequence, e.created_at, e.action FROM eventstore.events e "
+	filterColumnSelector = `SELECT e.id, e.aggregate, e.revision, e.payload, e.sequence, e.created_at, e.action, e.metadata, e.position::STRING, COALESCE(e.data_subject, ''), e.encrypted_payload, k."key", e.redacted_at, COALESCE(e.key_id, ''), e.signature, COALESCE(e.codec, 'json'), e.raw_payload, COALESCE(e.compression, ''), COALESCE(e.payload_ref, ''), e.tenant FROM {{schema}}.events e LEFT JOIN {{schema}}.data_keys k ON k.id = e.data_key_id `
 	filterLimit          = " LIMIT $"
+	filterTenant         = "e.tenant = $"
 	filterIgnoreOpenPush = `e.created_at < (SELECT COALESCE(MIN(start), NOW())::TIMESTAMPTZ FROM crdb_internal.cluster_transactions where application_name = $`
 )
 
-func (store *CockroachDB) prepareStatement(filter *eventstore.Filter) (builder strings.Builder, args []any) {
+// prepareStatement builds the query of the filter
+// the events are scoped to the tenant unless [eventstore.Filter.CrossTenant] is set
+func (store *CockroachDB) prepareStatement(filter *eventstore.Filter, tenant string) (builder strings.Builder, args []any) {
 	var index int
 
 	builder.WriteString(filterColumnSelector)
 
 	builder.WriteString(" WHERE ")
+	if !filter.CrossTenant {
+		args = append(args, tenantClause(&builder, &index, tenant)...)
+		builder.WriteString(" AND ")
+	}
 	if len(filter.Queries) > 0 {
-		args = queriesToClause(&builder, &index, filter.Queries)
+		builder.WriteRune('(')
+		args = append(args, queriesToClause(&builder, &index, filter.Queries)...)
+		builder.WriteString(") AND ")
 	}
 
-	if len(args) >= 0 {
-		builder.WriteString(" AND ")
-	}
 	builder.WriteString(filterIgnoreOpenPush)
 	index++
 	builder.WriteString(strconv.Itoa(index))
@@ -118,6 +275,14 @@ func (store *CockroachDB) prepareStatement(filter *eventstore.Filter) (builder s
 	return builder, args
 }
 
+// tenantClause restricts the events to the tenant
+func tenantClause(builder *strings.Builder, index *int, tenant string) []any {
+	builder.WriteString(filterTenant)
+	*index++
+	builder.WriteString(strconv.Itoa(*index))
+	return []any{tenant}
+}
+
 func queriesToClause(builder *strings.Builder, index *int, queries []*eventstore.FilterQuery) (args []any) {
 	for i, query := range queries {
 
@@ -136,6 +301,7 @@ var (
 	filterSequenceLt  = " AND e.sequence < $"
 	filterCreatedAtGt = " AND e.created_at > $"
 	filterCreatedAtLt = " AND e.created_at < $"
+	filterCorrelation = " AND e.correlation_id = $"
 )
 
 func queryToClause(builder *strings.Builder, index *int, query *eventstore.FilterQuery) []any {
@@ -171,12 +337,19 @@ func queryToClause(builder *strings.Builder, index *int, query *eventstore.Filte
 		args = append(args, query.CreatedAt.To)
 	}
 
+	if query.CorrelationID != "" {
+		builder.WriteString(filterCorrelation)
+		*index++
+		builder.WriteString(strconv.Itoa(*index))
+		args = append(args, query.CorrelationID)
+	}
+
 	builder.WriteRune(')')
 
 	return args
 }
 
-var filterActionsCondition = "e.id IN (SELECT a.event FROM eventstore.actions a"
+var filterActionsCondition = "e.id IN (SELECT a.event FROM {{schema}}.actions a"
 
 func subjectsToClause(builder *strings.Builder, index *int, subjects []eventstore.Subject) []any {
 	if len(subjects) == 0 {
@@ -222,7 +395,7 @@ func subjectsToJoins(builder *strings.Builder, index *int, subjects []eventstore
 			continue
 		}
 		tableAlias := "a" + strconv.Itoa(depth)
-		builder.WriteString(" JOIN eventstore.actions ")
+		builder.WriteString(" JOIN {{schema}}.actions ")
 		builder.WriteString(tableAlias)
 		builder.WriteString(" ON a.event = ")
 		builder.WriteString(tableAlias)
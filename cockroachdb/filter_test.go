@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -17,10 +18,111 @@ func Benchmark_Filter(b *testing.B) {
 	})
 }
 
+func Benchmark_Filter_LargePayload(b *testing.B) {
+	ctx := context.Background()
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("document"), eventstore.MultiToken}},
+		},
+	}
+	for _, bench := range compressionBenchStores() {
+		b.Run(bench.name, func(b *testing.B) {
+			if err := store.Before(ctx, b); err != nil {
+				b.Fatal("unable to execute store.Before: ", err)
+			}
+			for i := range 100 {
+				if err := bench.store.Push(ctx, largeDocumentAggregate(strconv.Itoa(i))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			reportStoredPayloadSize(ctx, b)
+
+			b.ResetTimer()
+			for range b.N {
+				for event, err := range bench.store.FilterIter(ctx, filter) {
+					if err != nil {
+						b.Fatal(err)
+					}
+					if err = event.UnmarshalPayload(&map[string]string{}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
 func Test_Filter_Compliance(t *testing.T) {
 	eventstore.FilterComplianceTests(context.Background(), t, store)
 }
 
+func Test_Tenant_Compliance(t *testing.T) {
+	eventstore.TenantComplianceTests(context.Background(), t, store)
+}
+
+func Test_prepareStatement(t *testing.T) {
+	type want struct {
+		stmt string
+		args []any
+	}
+	tests := []struct {
+		name   string
+		filter *eventstore.Filter
+		tenant string
+		want   want
+	}{
+		{
+			name:   "no queries",
+			filter: &eventstore.Filter{},
+			want: want{
+				stmt: filterColumnSelector + ` WHERE e.tenant = $1 AND ` + filterIgnoreOpenPush + `2) ORDER BY e.position, e.in_tx_order`,
+				args: []any{"", "es_push"},
+			},
+		},
+		{
+			name: "queries of tenant",
+			filter: &eventstore.Filter{
+				Queries: []*eventstore.FilterQuery{
+					{Subjects: []eventstore.Subject{eventstore.SingleToken}},
+					{Subjects: []eventstore.Subject{eventstore.SingleToken, eventstore.SingleToken}},
+				},
+				Limit: 10,
+			},
+			tenant: "tenant",
+			want: want{
+				stmt: filterColumnSelector + ` WHERE e.tenant = $1 AND ((e.action_depth = $2) OR (e.action_depth = $3)) AND ` + filterIgnoreOpenPush + `4) ORDER BY e.position, e.in_tx_order LIMIT $5`,
+				args: []any{"tenant", 1, 2, "es_push", uint64(10)},
+			},
+		},
+		{
+			name: "cross tenant",
+			filter: &eventstore.Filter{
+				Queries: []*eventstore.FilterQuery{
+					{Subjects: []eventstore.Subject{eventstore.SingleToken}},
+				},
+				CrossTenant: true,
+			},
+			tenant: "tenant",
+			want: want{
+				stmt: filterColumnSelector + ` WHERE ((e.action_depth = $1)) AND ` + filterIgnoreOpenPush + `2) ORDER BY e.position, e.in_tx_order`,
+				args: []any{1, "es_push"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := New(&Config{})
+			builder, args := store.prepareStatement(tt.filter, tt.tenant)
+			if stmt := builder.String(); stmt != tt.want.stmt {
+				t.Errorf("unexpected stmt want:\n%q\ngot:\n%q", tt.want.stmt, stmt)
+			}
+			if !reflect.DeepEqual(args, tt.want.args) {
+				t.Errorf("unexpected args want:\n%v\ngot:\n%v", tt.want.args, args)
+			}
+		})
+	}
+}
+
 func Test_textSubjectClause(t *testing.T) {
 	type args struct {
 		index      int
@@ -119,7 +221,7 @@ func Test_queriesToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "(e.id IN (SELECT a.event FROM eventstore.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3)",
+				query: "(e.id IN (SELECT a.event FROM {{schema}}.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3)",
 				args: []any{
 					eventstore.TextSubject("user"),
 					0,
@@ -142,7 +244,7 @@ func Test_queriesToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "(e.id IN (SELECT a.event FROM eventstore.actions a JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5)",
+				query: "(e.id IN (SELECT a.event FROM {{schema}}.actions a JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5)",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -168,7 +270,7 @@ func Test_queriesToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "(e.id IN (SELECT a.event FROM eventstore.actions a JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 JOIN eventstore.actions a1 ON a.event = a1.event AND a1.action = $3 AND a1.depth = $4 WHERE a.action = $5 AND a.depth = $6) AND e.action_depth = $7)",
+				query: "(e.id IN (SELECT a.event FROM {{schema}}.actions a JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 JOIN {{schema}}.actions a1 ON a.event = a1.event AND a1.action = $3 AND a1.depth = $4 WHERE a.action = $5 AND a.depth = $6) AND e.action_depth = $7)",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -201,7 +303,7 @@ func Test_queriesToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "(e.id IN (SELECT a.event FROM eventstore.actions a JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5) OR (e.id IN (SELECT a.event FROM eventstore.actions a JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $6 AND a0.depth = $7 WHERE a.action = $8 AND a.depth = $9) AND e.action_depth = $10)",
+				query: "(e.id IN (SELECT a.event FROM {{schema}}.actions a JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5) OR (e.id IN (SELECT a.event FROM {{schema}}.actions a JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $6 AND a0.depth = $7 WHERE a.action = $8 AND a.depth = $9) AND e.action_depth = $10)",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -448,7 +550,7 @@ func Test_subjectsToJoins(t *testing.T) {
 				},
 			},
 			want: want{
-				query: " JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2",
+				query: " JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -466,7 +568,7 @@ func Test_subjectsToJoins(t *testing.T) {
 				},
 			},
 			want: want{
-				query: " JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 JOIN eventstore.actions a1 ON a.event = a1.event AND a1.action = $3 AND a1.depth = $4",
+				query: " JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 JOIN {{schema}}.actions a1 ON a.event = a1.event AND a1.action = $3 AND a1.depth = $4",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -500,7 +602,7 @@ func Test_subjectsToJoins(t *testing.T) {
 				},
 			},
 			want: want{
-				query: " JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2",
+				query: " JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -518,7 +620,7 @@ func Test_subjectsToJoins(t *testing.T) {
 				},
 			},
 			want: want{
-				query: " JOIN eventstore.actions a1 ON a.event = a1.event AND a1.action = $1 AND a1.depth = $2",
+				query: " JOIN {{schema}}.actions a1 ON a.event = a1.event AND a1.action = $1 AND a1.depth = $2",
 				args: []any{
 					eventstore.TextSubject("added"),
 					2,
@@ -537,7 +639,7 @@ func Test_subjectsToJoins(t *testing.T) {
 				},
 			},
 			want: want{
-				query: " JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 JOIN eventstore.actions a2 ON a.event = a2.event AND a2.action = $3 AND a2.depth = $4",
+				query: " JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 JOIN {{schema}}.actions a2 ON a.event = a2.event AND a2.action = $3 AND a2.depth = $4",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -571,7 +673,7 @@ func Test_subjectsToJoins(t *testing.T) {
 				},
 			},
 			want: want{
-				query: " JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2",
+				query: " JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -624,7 +726,7 @@ func Test_queryToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "(e.id IN (SELECT a.event FROM eventstore.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3)",
+				query: "(e.id IN (SELECT a.event FROM {{schema}}.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3)",
 				args: []any{
 					eventstore.TextSubject("user"),
 					0,
@@ -651,6 +753,26 @@ func Test_queryToClause(t *testing.T) {
 				index: 1,
 			},
 		},
+		{
+			name: "correlation id",
+			args: args{
+				index: 0,
+				query: &eventstore.FilterQuery{
+					Subjects: []eventstore.Subject{
+						eventstore.MultiToken,
+					},
+					CorrelationID: "correlation",
+				},
+			},
+			want: want{
+				query: "(e.action_depth >= $1 AND e.correlation_id = $2)",
+				args: []any{
+					1,
+					"correlation",
+				},
+				index: 2,
+			},
+		},
 	}
 	for _, tt := range tests {
 		var builder strings.Builder
@@ -819,7 +941,7 @@ func Test_subjectsToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "e.id IN (SELECT a.event FROM eventstore.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3",
+				query: "e.id IN (SELECT a.event FROM {{schema}}.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3",
 				args: []any{
 					eventstore.TextSubject("user"),
 					0,
@@ -838,7 +960,7 @@ func Test_subjectsToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "e.id IN (SELECT a.event FROM eventstore.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3",
+				query: "e.id IN (SELECT a.event FROM {{schema}}.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth = $3",
 				args: []any{
 					eventstore.TextSubject("user"),
 					0,
@@ -857,7 +979,7 @@ func Test_subjectsToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "e.id IN (SELECT a.event FROM eventstore.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth >= $3",
+				query: "e.id IN (SELECT a.event FROM {{schema}}.actions a WHERE a.action = $1 AND a.depth = $2) AND e.action_depth >= $3",
 				args: []any{
 					eventstore.TextSubject("user"),
 					0,
@@ -876,7 +998,7 @@ func Test_subjectsToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "e.id IN (SELECT a.event FROM eventstore.actions a JOIN eventstore.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5",
+				query: "e.id IN (SELECT a.event FROM {{schema}}.actions a JOIN {{schema}}.actions a0 ON a.event = a0.event AND a0.action = $1 AND a0.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5",
 				args: []any{
 					eventstore.TextSubject("id"),
 					1,
@@ -898,7 +1020,7 @@ func Test_subjectsToClause(t *testing.T) {
 				},
 			},
 			want: want{
-				query: "e.id IN (SELECT a.event FROM eventstore.actions a JOIN eventstore.actions a1 ON a.event = a1.event AND a1.action = $1 AND a1.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5",
+				query: "e.id IN (SELECT a.event FROM {{schema}}.actions a JOIN {{schema}}.actions a1 ON a.event = a1.event AND a1.action = $1 AND a1.depth = $2 WHERE a.action = $3 AND a.depth = $4) AND e.action_depth = $5",
 				args: []any{
 					eventstore.TextSubject("added"),
 					2,
@@ -927,3 +1049,149 @@ func Test_subjectsToClause(t *testing.T) {
 		})
 	}
 }
+
+type testBatchReducer struct {
+	size          int
+	byTransaction bool
+	batches       [][]uint32
+}
+
+// Reduce implements [eventstore.Reducer]
+func (r *testBatchReducer) Reduce(events ...eventstore.Event) error {
+	sequences := make([]uint32, len(events))
+	for i, event := range events {
+		sequences[i] = event.Sequence()
+	}
+	r.batches = append(r.batches, sequences)
+	return nil
+}
+
+// BatchSize implements [eventstore.BatchReducer]
+func (r *testBatchReducer) BatchSize() int {
+	return r.size
+}
+
+// BatchByTransaction implements [eventstore.BatchReducer]
+func (r *testBatchReducer) BatchByTransaction() bool {
+	return r.byTransaction
+}
+
+func Test_Filter_BatchReducer(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	// 2 transactions with 3 and 2 events
+	for _, count := range []int{3, 2} {
+		commands := make([]eventstore.Command, count)
+		for i := range commands {
+			commands[i] = &testCommand{
+				testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "changed"}, revision: 1},
+			}
+		}
+		if err := store.Push(ctx, &testAggregate{id: eventstore.TextSubjects{"user", "1"}, commands: commands}); err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+		},
+	}
+	tests := []struct {
+		name    string
+		reducer *testBatchReducer
+		want    [][]uint32
+	}{
+		{
+			name:    "size",
+			reducer: &testBatchReducer{size: 2},
+			want:    [][]uint32{{1, 2}, {3, 4}, {5}},
+		},
+		{
+			name:    "by transaction",
+			reducer: &testBatchReducer{byTransaction: true},
+			want:    [][]uint32{{1, 2, 3}, {4, 5}},
+		},
+		{
+			name:    "size and by transaction",
+			reducer: &testBatchReducer{size: 2, byTransaction: true},
+			want:    [][]uint32{{1, 2}, {3}, {4, 5}},
+		},
+		{
+			name:    "unlimited",
+			reducer: &testBatchReducer{},
+			want:    [][]uint32{{1, 2, 3, 4, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Filter(ctx, filter, tt.reducer); err != nil {
+				t.Fatalf("unable to filter events: %v", err)
+			}
+			if !reflect.DeepEqual(tt.reducer.batches, tt.want) {
+				t.Errorf("unexpected batches want: %v, got: %v", tt.want, tt.reducer.batches)
+			}
+		})
+	}
+}
+
+func Test_eventBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		batch          *eventBatch
+		next           *event
+		wantEndsBefore bool
+		wantIsFull     bool
+	}{
+		{
+			name:  "empty",
+			batch: &eventBatch{size: 1, byTransaction: true},
+			next:  &event{position: "1"},
+		},
+		{
+			name:       "full",
+			batch:      &eventBatch{size: 1, events: []*event{{position: "1"}}},
+			next:       &event{position: "2"},
+			wantIsFull: true,
+		},
+		{
+			name:  "unlimited",
+			batch: &eventBatch{events: []*event{{position: "1"}, {position: "1"}}},
+			next:  &event{position: "2"},
+		},
+		{
+			name:  "same transaction",
+			batch: &eventBatch{byTransaction: true, events: []*event{{position: "1"}}},
+			next:  &event{position: "1"},
+		},
+		{
+			name:           "next transaction",
+			batch:          &eventBatch{byTransaction: true, events: []*event{{position: "1"}}},
+			next:           &event{position: "2"},
+			wantEndsBefore: true,
+		},
+		{
+			name:  "same transaction different scale",
+			batch: &eventBatch{byTransaction: true, events: []*event{{position: "1760890000000000000.1"}}},
+			next:  &event{position: "1760890000000000000.1000000000"},
+		},
+		{
+			name:           "next transaction logical counter",
+			batch:          &eventBatch{byTransaction: true, events: []*event{{position: "1760890000000000000.0000000001"}}},
+			next:           &event{position: "1760890000000000000.0000000002"},
+			wantEndsBefore: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.batch.endsBefore(tt.next); got != tt.wantEndsBefore {
+				t.Errorf("unexpected endsBefore want: %v, got: %v", tt.wantEndsBefore, got)
+			}
+			if got := tt.batch.isFull(); got != tt.wantIsFull {
+				t.Errorf("unexpected isFull want: %v, got: %v", tt.wantIsFull, got)
+			}
+		})
+	}
+}
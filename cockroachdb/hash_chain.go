@@ -0,0 +1,445 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// payloadSaltSize is the size of the random key of the payload hash
+const payloadSaltSize = 32
+
+// hashPayloads computes the hashes of the payloads of the commands.
+// Encrypted payloads are hashed as stored, so the hash doesn't reveal the payload
+// after the data subject was forgotten.
+// Plain payloads are hashed using a random salt which is deleted on redaction,
+// so the hash of a redacted payload can't be used to guess the payload.
+func hashPayloads(ctx context.Context, commands []*command) (err error) {
+	for _, cmd := range commands {
+		cmd.payloadHash = nil
+		if cmd.encryptedPayload != nil {
+			// the payload is encrypted again if the transaction is retried
+			cmd.payloadSalt = nil
+			cmd.payloadHash = encryptedPayloadHash(cmd.encryptedPayload)
+			continue
+		}
+		if len(cmd.payload) == 0 {
+			continue
+		}
+		// the salt is kept if the transaction is retried
+		if cmd.payloadSalt == nil {
+			cmd.payloadSalt = make([]byte, payloadSaltSize)
+			if _, err = rand.Read(cmd.payloadSalt); err != nil {
+				logger.ErrorContext(ctx, "generate payload salt failed", "cause", err)
+				return err
+			}
+		}
+		if cmd.payloadHash, err = payloadHash(cmd.codec, cmd.payload, cmd.payloadSalt); err != nil {
+			logger.ErrorContext(ctx, "hash payload failed", "cause", err, "action", cmd.Action().Join("."))
+			return err
+		}
+	}
+	return nil
+}
+
+// payloadHash returns the hmac-sha256 of the payload encoded by codec keyed by salt
+// json is canonicalized using [eventstore.CanonicalJSON] because JSONB doesn't keep the formatting.
+// Payloads without salt, stored before the salt was introduced, are hashed using sha256.
+func payloadHash(codec string, payload, salt []byte) (_ []byte, err error) {
+	if len(payload) == 0 {
+		return nil, nil
+	}
+
+	if isJSON(codec) {
+		if payload, err = eventstore.CanonicalJSON(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if salt == nil {
+		hash := sha256.Sum256(payload)
+		return hash[:], nil
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// encryptedPayloadHash returns the sha256 of the encrypted payload
+func encryptedPayloadHash(encryptedPayload []byte) []byte {
+	hash := sha256.Sum256(encryptedPayload)
+	return hash[:]
+}
+
+// eventHash returns the sha256 over the fields of the event and the hash of the previous event
+// each field is prefixed with its length to prevent ambiguous concatenations
+func eventHash(previousHash []byte, aggregate, action eventstore.TextSubjects, revision uint16, sequence uint32, payloadHash, metadata []byte) []byte {
+	hash := sha256.New()
+	writeField := func(field []byte) {
+		hash.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		hash.Write(field)
+	}
+
+	writeField(previousHash)
+	for _, subjects := range []eventstore.TextSubjects{aggregate, action} {
+		writeField(binary.BigEndian.AppendUint32(nil, uint32(len(subjects))))
+		for _, subject := range subjects {
+			writeField([]byte(subject))
+		}
+	}
+	writeField(binary.BigEndian.AppendUint16(nil, revision))
+	writeField(binary.BigEndian.AppendUint32(nil, sequence))
+	writeField(payloadHash)
+	writeField(metadata)
+
+	return hash.Sum(nil)
+}
+
+// BrokenLink describes the first event of an aggregate whose hash chain is broken
+type BrokenLink struct {
+	EventID   string
+	Aggregate eventstore.TextSubjects
+	Sequence  uint32
+	Reason    string
+}
+
+func (link *BrokenLink) String() string {
+	return fmt.Sprintf("event %s (%s, sequence %d): %s", link.EventID, link.Aggregate.Join("."), link.Sequence, link.Reason)
+}
+
+const (
+	brokenSequence     = "sequence gap"
+	brokenHashMissing  = "hash missing"
+	brokenPreviousHash = "previous hash does not match"
+	brokenHash         = "hash does not match"
+	brokenPayload      = "payload does not match its hash"
+	brokenRedaction    = "redaction is not audited"
+)
+
+var (
+	verifyHashChainStmt    = `SELECT e.id, e."aggregate", e.action, e.revision, e."sequence", e.payload, COALESCE(e.codec, 'json'), e.raw_payload, COALESCE(e.compression, ''), COALESCE(e.payload_ref, ''), e.metadata, e.payload_hash, e.payload_salt, e.hash, e.previous_hash, COALESCE(e.data_subject, ''), e.encrypted_payload, k."key", e.redacted_at IS NOT NULL FROM {{schema}}.events e LEFT JOIN {{schema}}.data_keys k ON k.id = e.data_key_id`
+	verifyHashChainOrderBy = ` ORDER BY e."aggregate", e."sequence"`
+	// the audit events of all tenants are loaded because cross tenant redactions
+	// are audited in the tenant of the actor
+	auditedRedactionsStmt = verifyHashChainStmt + ` WHERE e."aggregate" = $1 AND e.action = $2 AND e.hash IS NOT NULL AND e.redacted_at IS NULL`
+)
+
+// chainedEvent contains the columns of an event required to verify the hash chain
+type chainedEvent struct {
+	id           string
+	aggregate    eventstore.TextSubjects
+	action       eventstore.TextSubjects
+	revision     uint16
+	sequence     uint32
+	payload      []byte
+	codec        string
+	rawPayload   []byte
+	compression  string
+	payloadRef   string
+	metadata     *eventstore.Metadata
+	payloadHash  []byte
+	payloadSalt  []byte
+	hash         []byte
+	previousHash []byte
+
+	dataSubject      string
+	encryptedPayload []byte
+	dataKey          []byte
+	isRedacted       bool
+	// isRedactionAudited is true if the event is listed in an audit event of [RedactedAction]
+	isRedactionAudited bool
+	// isBlobInvalid is true if the offloaded payload is missing or doesn't match its key
+	isBlobInvalid bool
+}
+
+// VerifyHashChain walks the events of the aggregates ordered by sequence
+// and returns the first broken link of the hash chain of each aggregate.
+// If no aggregates are passed all events are verified.
+// Only events of [eventstore.TenantFromContext] are verified.
+// Events stored before [WithHashChain] was enabled are skipped.
+//
+// The encrypted payloads of data subjects are verified as stored,
+// therefore the payloads of forgotten data subjects are verified as well.
+// The payloads of redacted events are only accepted if the event is listed in an audit event
+// of [RedactedAction] whose hash and payload are intact.
+// The links of the audit events are verified as part of [RedactionAggregate].
+func (store *CockroachDB) VerifyHashChain(ctx context.Context, aggregates ...eventstore.TextSubjects) (broken []*BrokenLink, err error) {
+	audited, err := store.auditedRedactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, args := verifyHashChainStatement(eventstore.TenantFromContext(ctx), aggregates)
+
+	rows, err := store.client.Query(ctx, store.qualify(stmt), args...)
+	if err != nil {
+		logger.ErrorContext(ctx, "query hash chain failed", "cause", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		previous *chainedEvent
+		// isBroken is true if a broken link of the current aggregate was found
+		isBroken bool
+	)
+	for rows.Next() {
+		event, err := store.scanChainedEvent(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		_, event.isRedactionAudited = audited[event.id]
+
+		if previous != nil && !slices.Equal(previous.aggregate, event.aggregate) {
+			previous, isBroken = nil, false
+		}
+		if !isBroken {
+			if reason := event.verify(previous); reason != "" {
+				isBroken = true
+				broken = append(broken, &BrokenLink{
+					EventID:   event.id,
+					Aggregate: event.aggregate,
+					Sequence:  event.sequence,
+					Reason:    reason,
+				})
+			}
+		}
+		previous = event
+	}
+	if err = rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "read hash chain failed", "cause", err)
+		return nil, err
+	}
+
+	return broken, nil
+}
+
+// scanChainedEvent scans the columns of [verifyHashChainStmt]
+// and fetches the offloaded payload of the event
+func (store *CockroachDB) scanChainedEvent(ctx context.Context, rows pgx.Rows) (*chainedEvent, error) {
+	event := new(chainedEvent)
+	err := rows.Scan(
+		&event.id,
+		&event.aggregate,
+		&event.action,
+		&event.revision,
+		&event.sequence,
+		&event.payload,
+		&event.codec,
+		&event.rawPayload,
+		&event.compression,
+		&event.payloadRef,
+		&event.metadata,
+		&event.payloadHash,
+		&event.payloadSalt,
+		&event.hash,
+		&event.previousHash,
+		&event.dataSubject,
+		&event.encryptedPayload,
+		&event.dataKey,
+		&event.isRedacted,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "scan of hash chain failed", "cause", err)
+		return nil, err
+	}
+	if err = store.loadChainedBlob(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// auditedRedactions returns the ids of the events listed in the audit events of [RedactedAction]
+// audit events whose hash or payload doesn't match are ignored
+func (store *CockroachDB) auditedRedactions(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := store.client.Query(ctx, store.qualify(auditedRedactionsStmt), RedactionAggregate, RedactedAction)
+	if err != nil {
+		logger.ErrorContext(ctx, "query redaction audit failed", "cause", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	audited := make(map[string]struct{})
+	for rows.Next() {
+		event, err := store.scanChainedEvent(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		if !event.isHashValid() {
+			logger.WarnContext(ctx, "redaction audit ignored", "id", event.id, "reason", brokenHash)
+			continue
+		}
+		payload, reason := event.verifyPayload()
+		if reason != "" {
+			logger.WarnContext(ctx, "redaction audit ignored", "id", event.id, "reason", reason)
+			continue
+		}
+		codec, ok := store.codecs[event.codec]
+		if !ok {
+			logger.ErrorContext(ctx, "decode redaction audit failed", "id", event.id, "codec", event.codec)
+			return nil, fmt.Errorf("%w: %q", eventstore.ErrUnknownCodec, event.codec)
+		}
+		var redacted RedactedPayload
+		if err = codec.Unmarshal(payload, &redacted); err != nil {
+			logger.ErrorContext(ctx, "decode redaction audit failed", "cause", err, "id", event.id)
+			return nil, err
+		}
+		for _, id := range redacted.EventIDs {
+			audited[id] = struct{}{}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "read redaction audit failed", "cause", err)
+		return nil, err
+	}
+
+	return audited, nil
+}
+
+// loadChainedBlob fetches the offloaded payload of the event
+// missing or modified blobs mark the payload as invalid
+func (store *CockroachDB) loadChainedBlob(ctx context.Context, event *chainedEvent) error {
+	if event.payloadRef == "" || event.isRedacted {
+		return nil
+	}
+	payload, err := loadBlob(ctx, store.blobs, event.payloadRef)
+	if errors.Is(err, eventstore.ErrBlobNotFound) || errors.Is(err, eventstore.ErrBlobHashMismatch) {
+		event.isBlobInvalid = true
+		return nil
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "load offloaded payload failed", "cause", err, "id", event.id)
+		return err
+	}
+	if event.dataSubject != "" {
+		event.encryptedPayload = payload
+	} else {
+		event.rawPayload = payload
+	}
+	return nil
+}
+
+func verifyHashChainStatement(tenant string, aggregates []eventstore.TextSubjects) (string, []any) {
+	var (
+		builder strings.Builder
+		index   int
+	)
+	builder.WriteString(verifyHashChainStmt)
+	builder.WriteString(" WHERE ")
+	args := tenantClause(&builder, &index, tenant)
+	if len(aggregates) > 0 {
+		builder.WriteString(" AND (")
+		for i, aggregate := range aggregates {
+			if i > 0 {
+				builder.WriteString(" OR ")
+			}
+			index++
+			builder.WriteString(`e."aggregate" = $` + strconv.Itoa(index))
+			args = append(args, aggregate)
+		}
+		builder.WriteRune(')')
+	}
+	builder.WriteString(verifyHashChainOrderBy)
+
+	return builder.String(), args
+}
+
+// verify returns the reason why the link between previous and e is broken
+// an empty string is returned if the link is valid
+func (e *chainedEvent) verify(previous *chainedEvent) string {
+	var previousSequence uint32
+	var previousHash []byte
+	if previous != nil {
+		previousSequence, previousHash = previous.sequence, previous.hash
+	}
+
+	if e.sequence != previousSequence+1 {
+		return brokenSequence
+	}
+	if e.hash == nil {
+		if previousHash != nil {
+			return brokenHashMissing
+		}
+		// the event was stored before the hash chain was enabled
+		return ""
+	}
+	if !bytes.Equal(e.previousHash, previousHash) {
+		return brokenPreviousHash
+	}
+	if !e.isHashValid() {
+		return brokenHash
+	}
+
+	if e.isRedacted {
+		if !e.isRedactionAudited {
+			return brokenRedaction
+		}
+		return ""
+	}
+	_, reason := e.verifyPayload()
+	return reason
+}
+
+// isHashValid checks if the hash of the event matches its fields
+func (e *chainedEvent) isHashValid() bool {
+	var metadata []byte
+	if e.metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(e.metadata); err != nil {
+			return false
+		}
+	}
+	return bytes.Equal(e.hash, eventHash(e.previousHash, e.aggregate, e.action, e.revision, e.sequence, e.payloadHash, metadata))
+}
+
+// verifyPayload returns the plain payload of the event
+// and the reason why it doesn't match the payload hash
+// the plain payload of forgotten data subjects is nil
+func (e *chainedEvent) verifyPayload() (payload []byte, reason string) {
+	if e.isBlobInvalid {
+		return nil, brokenPayload
+	}
+
+	var err error
+	if e.dataSubject != "" {
+		if !bytes.Equal(encryptedPayloadHash(e.encryptedPayload), e.payloadHash) {
+			return nil, brokenPayload
+		}
+		if e.dataKey == nil {
+			// the data subject was forgotten
+			return nil, ""
+		}
+		if payload, err = decrypt(e.dataKey, e.encryptedPayload, e.dataSubject); err != nil {
+			return nil, brokenPayload
+		}
+		if payload, err = decompress(e.compression, payload); err != nil {
+			return nil, brokenPayload
+		}
+		return payload, ""
+	}
+
+	payload = e.payload
+	if e.rawPayload != nil {
+		payload = e.rawPayload
+	}
+	if payload, err = decompress(e.compression, payload); err != nil {
+		return nil, brokenPayload
+	}
+	if hash, err := payloadHash(e.codec, payload, e.payloadSalt); err != nil || !bytes.Equal(hash, e.payloadHash) {
+		return nil, brokenPayload
+	}
+	return payload, ""
+}
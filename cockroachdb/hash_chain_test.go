@@ -0,0 +1,347 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+func Test_VerifyHashChain(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	chained := New(&Config{Pool: store.client}, WithHashChain())
+
+	for _, name := range []string{"gigi", "gugu"} {
+		err := chained.Push(ctx, &testAggregate{
+			id: eventstore.TextSubjects{"user", "1"},
+			commands: []eventstore.Command{
+				&testCommand{
+					testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "changed"}, revision: 1},
+					payload:    map[string]string{"name": name},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+	}
+
+	broken, err := chained.VerifyHashChain(ctx)
+	if err != nil {
+		t.Fatalf("unable to verify hash chain: %v", err)
+	}
+	if len(broken) > 0 {
+		t.Fatalf("expected intact hash chain, got: %v", broken)
+	}
+
+	_, err = store.client.Exec(ctx, `UPDATE eventstore.events SET payload = '{"name": "tampered"}' WHERE "sequence" = 1`)
+	if err != nil {
+		t.Fatalf("unable to tamper event: %v", err)
+	}
+
+	broken, err = chained.VerifyHashChain(ctx, eventstore.TextSubjects{"user", "1"})
+	if err != nil {
+		t.Fatalf("unable to verify hash chain: %v", err)
+	}
+	if len(broken) != 1 {
+		t.Fatalf("expected 1 broken link, got: %v", broken)
+	}
+	if broken[0].Sequence != 1 || broken[0].Reason != brokenPayload {
+		t.Errorf("unexpected broken link: %v", broken[0])
+	}
+}
+
+func Test_VerifyHashChain_redacted(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	chained := New(&Config{Pool: store.client}, WithHashChain())
+
+	for _, id := range []eventstore.TextSubject{"1", "2"} {
+		err := chained.Push(ctx, &testAggregate{
+			id: eventstore.TextSubjects{"user", id},
+			commands: []eventstore.Command{
+				&testCommand{
+					testAction: &testAction{action: eventstore.TextSubjects{"user", id, "added"}, revision: 1},
+					payload:    map[string]string{"name": "gigi"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+	}
+
+	_, err := chained.RedactEvents(ctx, &Redaction{
+		Filter: &eventstore.Filter{
+			Queries: []*eventstore.FilterQuery{
+				{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+			},
+		},
+		Reason: "gdpr",
+	})
+	if err != nil {
+		t.Fatalf("unable to redact events: %v", err)
+	}
+	// the redaction is hidden from the audit
+	_, err = store.client.Exec(ctx, `UPDATE eventstore.events SET payload = NULL, redacted_at = now() WHERE "aggregate" = ARRAY['user', '2']`)
+	if err != nil {
+		t.Fatalf("unable to tamper event: %v", err)
+	}
+
+	broken, err := chained.VerifyHashChain(ctx)
+	if err != nil {
+		t.Fatalf("unable to verify hash chain: %v", err)
+	}
+	if len(broken) != 1 {
+		t.Fatalf("expected 1 broken link, got: %v", broken)
+	}
+	if !slices.Equal(broken[0].Aggregate, eventstore.TextSubjects{"user", "2"}) || broken[0].Reason != brokenRedaction {
+		t.Errorf("unexpected broken link: %v", broken[0])
+	}
+}
+
+func Test_payloadHash(t *testing.T) {
+	salt := []byte("salt")
+	want, err := payloadHash(eventstore.JSONCodec.Name(), []byte(`{"name":"gigi","age":42}`), salt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		codec   string
+		payload []byte
+		salt    []byte
+		isEqual bool
+	}{
+		{
+			name:    "formatted",
+			codec:   eventstore.JSONCodec.Name(),
+			payload: []byte(`{ "age": 42, "name": "gigi" }`),
+			salt:    salt,
+			isEqual: true,
+		},
+		{
+			name:    "changed",
+			codec:   eventstore.JSONCodec.Name(),
+			payload: []byte(`{"name":"gugu","age":42}`),
+			salt:    salt,
+			isEqual: false,
+		},
+		{
+			name:    "number format",
+			codec:   eventstore.JSONCodec.Name(),
+			payload: []byte(`{"name":"gigi","age":4.20E1}`),
+			salt:    salt,
+			isEqual: true,
+		},
+		{
+			name:    "binary codec",
+			codec:   "binary",
+			payload: []byte(`{ "age": 42, "name": "gigi" }`),
+			salt:    salt,
+			isEqual: false,
+		},
+		{
+			name:    "other salt",
+			codec:   eventstore.JSONCodec.Name(),
+			payload: []byte(`{"name":"gigi","age":42}`),
+			salt:    []byte("pepper"),
+			isEqual: false,
+		},
+		{
+			name:    "without salt",
+			codec:   eventstore.JSONCodec.Name(),
+			payload: []byte(`{"name":"gigi","age":42}`),
+			isEqual: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := payloadHash(tt.codec, tt.payload, tt.salt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bytes.Equal(got, want) != tt.isEqual {
+				t.Errorf("expected equal hashes to be %v", tt.isEqual)
+			}
+		})
+	}
+}
+
+func Test_chainedEvent_verify(t *testing.T) {
+	newEvent := func(previous *chainedEvent, sequence uint32, payload []byte) *chainedEvent {
+		event := &chainedEvent{
+			aggregate:   eventstore.TextSubjects{"user", "1"},
+			action:      eventstore.TextSubjects{"user", "1", "added"},
+			revision:    1,
+			sequence:    sequence,
+			payload:     payload,
+			codec:       eventstore.JSONCodec.Name(),
+			metadata:    &eventstore.Metadata{Creator: "gigi"},
+			payloadSalt: []byte("salt"),
+		}
+		if previous != nil {
+			event.previousHash = previous.hash
+		}
+		event.payloadHash, _ = payloadHash(event.codec, payload, event.payloadSalt)
+		metadata, _ := json.Marshal(event.metadata)
+		event.hash = eventHash(event.previousHash, event.aggregate, event.action, event.revision, event.sequence, event.payloadHash, metadata)
+		return event
+	}
+	first := newEvent(nil, 1, []byte(`{"name":"gigi"}`))
+
+	tests := []struct {
+		name     string
+		previous *chainedEvent
+		event    func() *chainedEvent
+		want     string
+	}{
+		{
+			name:  "first",
+			event: func() *chainedEvent { return first },
+		},
+		{
+			name:     "linked",
+			previous: first,
+			event:    func() *chainedEvent { return newEvent(first, 2, nil) },
+		},
+		{
+			name:  "not chained",
+			event: func() *chainedEvent { return &chainedEvent{sequence: 1} },
+		},
+		{
+			name:     "sequence gap",
+			previous: first,
+			event:    func() *chainedEvent { return newEvent(first, 3, nil) },
+			want:     brokenSequence,
+		},
+		{
+			name:     "hash missing",
+			previous: first,
+			event:    func() *chainedEvent { return &chainedEvent{sequence: 2} },
+			want:     brokenHashMissing,
+		},
+		{
+			name:     "previous hash",
+			previous: first,
+			event:    func() *chainedEvent { return newEvent(nil, 2, nil) },
+			want:     brokenPreviousHash,
+		},
+		{
+			name: "hash",
+			event: func() *chainedEvent {
+				event := newEvent(nil, 1, nil)
+				event.revision = 2
+				return event
+			},
+			want: brokenHash,
+		},
+		{
+			name: "payload",
+			event: func() *chainedEvent {
+				event := newEvent(nil, 1, []byte(`{"name":"gigi"}`))
+				event.payload = []byte(`{"name":"gugu"}`)
+				return event
+			},
+			want: brokenPayload,
+		},
+		{
+			name: "redacted payload",
+			event: func() *chainedEvent {
+				event := newEvent(nil, 1, []byte(`{"name":"gigi"}`))
+				event.payload, event.isRedacted, event.isRedactionAudited = nil, true, true
+				return event
+			},
+		},
+		{
+			name: "redacted payload without audit",
+			event: func() *chainedEvent {
+				event := newEvent(nil, 1, []byte(`{"name":"gigi"}`))
+				event.payload, event.isRedacted = nil, true
+				return event
+			},
+			want: brokenRedaction,
+		},
+		{
+			name: "salt deleted",
+			event: func() *chainedEvent {
+				event := newEvent(nil, 1, []byte(`{"name":"gigi"}`))
+				event.payloadSalt = nil
+				return event
+			},
+			want: brokenPayload,
+		},
+		{
+			name: "forgotten payload",
+			event: func() *chainedEvent {
+				event := newEvent(nil, 1, nil)
+				event.dataSubject, event.encryptedPayload = "gigi", []byte("encrypted")
+				event.payloadHash = encryptedPayloadHash(event.encryptedPayload)
+				event.hash = eventHash(nil, event.aggregate, event.action, event.revision, event.sequence, event.payloadHash, []byte(`{"creator":"gigi"}`))
+				return event
+			},
+		},
+		{
+			name: "forgotten payload changed",
+			event: func() *chainedEvent {
+				event := newEvent(nil, 1, nil)
+				event.dataSubject, event.encryptedPayload = "gigi", []byte("encrypted")
+				event.payloadHash = encryptedPayloadHash([]byte("original"))
+				event.hash = eventHash(nil, event.aggregate, event.action, event.revision, event.sequence, event.payloadHash, []byte(`{"creator":"gigi"}`))
+				return event
+			},
+			want: brokenPayload,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event().verify(tt.previous); got != tt.want {
+				t.Errorf("unexpected reason want: %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_verifyHashChainStatement(t *testing.T) {
+	tests := []struct {
+		name       string
+		tenant     string
+		aggregates []eventstore.TextSubjects
+		wantStmt   string
+		wantArgs   []any
+	}{
+		{
+			name:     "all",
+			tenant:   "tenant",
+			wantStmt: verifyHashChainStmt + ` WHERE e.tenant = $1` + verifyHashChainOrderBy,
+			wantArgs: []any{"tenant"},
+		},
+		{
+			name:       "aggregates",
+			aggregates: []eventstore.TextSubjects{{"user", "1"}, {"user", "2"}},
+			wantStmt:   verifyHashChainStmt + ` WHERE e.tenant = $1 AND (e."aggregate" = $2 OR e."aggregate" = $3)` + verifyHashChainOrderBy,
+			wantArgs:   []any{"", eventstore.TextSubjects{"user", "1"}, eventstore.TextSubjects{"user", "2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, args := verifyHashChainStatement(tt.tenant, tt.aggregates)
+			if stmt != tt.wantStmt {
+				t.Errorf("unexpected stmt want:\n%q\ngot:\n%q", tt.wantStmt, stmt)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("unexpected args want:\n%v\ngot:\n%v", tt.wantArgs, args)
+			}
+		})
+	}
+}
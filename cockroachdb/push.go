@@ -11,6 +11,7 @@ import (
 
 	crdb "github.com/cockroachdb/cockroach-go/v2/crdb/crdbpgxv5"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/adlerhurst/eventstore/v2"
 )
@@ -22,50 +23,115 @@ var pushTxOptions = pgx.TxOptions{
 
 // Push implements [eventstore.Eventstore]
 func (store *CockroachDB) Push(ctx context.Context, aggregates ...eventstore.Aggregate) (err error) {
-	indexes := prepareIndexes(aggregates)
+	indexes := prepareIndexes(ctx, aggregates)
 
-	commands, close, err := commandsFromAggregates(ctx, aggregates)
+	commands, close, err := store.commandsFromAggregates(ctx, aggregates)
 	if err != nil {
 		return err
 	}
 	defer close()
 
-	conn, err := store.client.Acquire(ctx)
+	conn, err := store.acquirePushConn(ctx)
 	if err != nil {
-		logger.ErrorContext(ctx, "acquire connection failed", "cause", err)
 		return err
 	}
 	defer conn.Release()
 
+	return crdb.ExecuteTx(ctx, conn, pushTxOptions, func(tx pgx.Tx) error {
+		return store.pushInTx(ctx, tx, indexes, commands)
+	})
+}
+
+// acquirePushConn acquires a connection which is marked as push connection
+func (store *CockroachDB) acquirePushConn(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := store.client.Acquire(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "acquire connection failed", "cause", err)
+		return nil, err
+	}
+
 	// The application_name is required to differentiate between filter and push queries
 	// filter queries are not relevant for the [filterIgnoreOpenPush] clause
 	_, err = conn.Exec(ctx, "SET application_name = $1", store.pushAppName)
 	if err != nil {
+		conn.Release()
 		logger.ErrorContext(ctx, "set application name failed", "cause", err)
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// pushInTx checks the preconditions and stores the commands inside tx
+// it must be repeatable because the transaction might be retried
+func (store *CockroachDB) pushInTx(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes, commands []*command) error {
+	indexes.reset()
+	indexes.hashChain = store.hashChain
+
+	pending, err := store.deduplicate(ctx, tx, indexes, commands)
+	if err != nil {
 		return err
 	}
 
-	return crdb.ExecuteTx(ctx, conn, pushTxOptions, func(tx pgx.Tx) error {
-		if err = currentSequences(ctx, tx, indexes); err != nil {
+	if err = store.currentSequences(ctx, tx, indexes); err != nil {
+		return err
+	}
+
+	if err = store.appendConditions(ctx, tx, indexes.conditions); err != nil {
+		return err
+	}
+
+	if err = store.uniqueConstraints(ctx, tx, pending); err != nil {
+		return err
+	}
+
+	if err = store.encryptPayloads(ctx, tx, pending); err != nil {
+		return err
+	}
+
+	if indexes.hashChain {
+		if err = hashPayloads(ctx, pending); err != nil {
 			return err
 		}
+	}
 
-		return push(ctx, tx, indexes, commands)
-	})
+	if store.signer != nil {
+		if err = signCommands(ctx, store.signer, pending); err != nil {
+			return err
+		}
+	}
+
+	if store.blobs != nil && store.blobLimit > 0 {
+		err = offloadPayloads(ctx, store.blobs, store.blobLimit, pending, func(ctx context.Context, refs []string) error {
+			return store.claimBlobs(ctx, tx, refs)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+	return store.push(ctx, tx, indexes, pending)
 }
 
 var (
-	currentSequencesPrefix = []byte(`SELECT "sequence", "aggregate" FROM eventstore.events WHERE ("sequence", "aggregate") IN (SELECT (max("sequence"), "aggregate") FROM eventstore.events WHERE `)
-	currentSequencesSuffix = []byte(` GROUP BY "aggregate") FOR UPDATE`)
+	currentSequencesPrefix = []byte(`SELECT "sequence", tenant, "aggregate", hash FROM {{schema}}.events WHERE (tenant, "aggregate", "sequence") IN (SELECT tenant, "aggregate", max("sequence") FROM {{schema}}.events WHERE `)
+	currentSequencesSuffix = []byte(` GROUP BY tenant, "aggregate") FOR UPDATE`)
 )
 
-func currentSequences(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes) (err error) {
+func (store *CockroachDB) currentSequences(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes) (err error) {
+	if len(indexes.aggregates) == 0 {
+		return nil
+	}
+
 	var builder strings.Builder
 	builder.Write(currentSequencesPrefix)
 	indexes.currentSequencesClauses(&builder)
 	builder.Write(currentSequencesSuffix)
 
-	rows, err := tx.Query(ctx, builder.String(), indexes.toAggregateArgs()...)
+	rows, err := tx.Query(ctx, store.qualify(builder.String()), indexes.toAggregateArgs()...)
 	if err != nil {
 		logger.ErrorContext(ctx, "query current sequences failed", "cause", err)
 		return err
@@ -74,44 +140,56 @@ func currentSequences(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes)
 
 	for rows.Next() {
 		var (
+			tenant    string
 			aggregate eventstore.TextSubjects
 			sequence  uint32
+			hash      []byte
 		)
 
-		if err = rows.Scan(&sequence, &aggregate); err != nil {
+		if err = rows.Scan(&sequence, &tenant, &aggregate, &hash); err != nil {
 			logger.ErrorContext(ctx, "scan of sequences failed", "cause", err)
 			return err
 		}
 
-		aggIdx := indexes.byAggregate(aggregate)
+		aggIdx := indexes.byAggregate(tenant, aggregate)
 		aggIdx.index = sequence
+		aggIdx.hash = hash
 	}
 
 	// check is not made during scan to verify that non existing aggregates are also checked
+	var conflicts []*eventstore.SequenceConflict
 	for _, aggregate := range indexes.aggregates {
-		if aggregate.shouldCheckSequence && aggregate.index != aggregate.expectedSequence {
-			logger.DebugContext(ctx, "unexpected sequence", "expected", aggregate.expectedSequence, "got", aggregate.index)
-			return eventstore.ErrSequenceNotMatched
+		if aggregate.skipped || aggregate.expectation.Matches(aggregate.index) {
+			continue
 		}
+		logger.DebugContext(ctx, "unexpected sequence", "tenant", aggregate.tenant, "aggregate", aggregate.aggregate.Join("."), "expected", aggregate.expectation, "got", aggregate.index)
+		conflicts = append(conflicts, &eventstore.SequenceConflict{
+			Aggregate: aggregate.aggregate,
+			Expected:  aggregate.expectation,
+			Actual:    aggregate.index,
+		})
+	}
+	if len(conflicts) > 0 {
+		return &eventstore.SequenceNotMatchedError{Conflicts: conflicts}
 	}
 
 	return nil
 }
 
 var (
-	pushEventsPrefix = []byte(`WITH computed AS (SELECT hlc_to_timestamp(cluster_logical_timestamp()) created_at, cluster_logical_timestamp() "position"), input ("aggregate", "action", revision, payload, "sequence", in_tx_order) AS (VALUES `)
-	pushEventsSuffix = []byte(`) INSERT INTO eventstore.events (created_at, "position", "aggregate", "action", revision, payload, "sequence", in_tx_order) SELECT c.created_at, c."position", i."aggregate", i."action", i.revision, i.payload, i."sequence", i.in_tx_order FROM input i, computed c RETURNING id, created_at`)
+	pushEventsPrefix = []byte(`WITH computed AS (SELECT hlc_to_timestamp(cluster_logical_timestamp()) created_at, cluster_logical_timestamp() "position"), input ("aggregate", "action", revision, payload, "sequence", in_tx_order, metadata, idempotency_key, encrypted_payload, data_key_id, data_subject, payload_hash, hash, previous_hash, key_id, signature, codec, raw_payload, compression, payload_ref, tenant, payload_salt) AS (VALUES `)
+	pushEventsSuffix = []byte(`) INSERT INTO {{schema}}.events (created_at, "position", "aggregate", "action", revision, payload, "sequence", in_tx_order, metadata, idempotency_key, encrypted_payload, data_key_id, data_subject, payload_hash, hash, previous_hash, key_id, signature, codec, raw_payload, compression, payload_ref, tenant, payload_salt) SELECT c.created_at, c."position", i."aggregate", i."action", i.revision, i.payload, i."sequence", i.in_tx_order, i.metadata, i.idempotency_key, i.encrypted_payload, i.data_key_id, i.data_subject, i.payload_hash, i.hash, i.previous_hash, i.key_id, i.signature, i.codec, i.raw_payload, i.compression, i.payload_ref, i.tenant, i.payload_salt FROM input i, computed c RETURNING id, created_at, "position"::STRING`)
 
-	pushActionsPrefix = []byte(`INSERT INTO eventstore.actions ("event", "action", depth) VALUES `)
+	pushActionsPrefix = []byte(`INSERT INTO {{schema}}.actions ("event", "action", depth) VALUES `)
 )
 
-func push(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes, commands []*command) (err error) {
+func (store *CockroachDB) push(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes, commands []*command) (err error) {
 	var pushBuilder strings.Builder
 	pushBuilder.Write(pushEventsPrefix)
 	eventsArgs := indexes.eventValues(commands, &pushBuilder)
 	pushBuilder.Write(pushEventsSuffix)
 
-	rows, err := tx.Query(ctx, pushBuilder.String(), eventsArgs...)
+	rows, err := tx.Query(ctx, store.qualify(pushBuilder.String()), eventsArgs...)
 	if err != nil {
 		logger.ErrorContext(ctx, "store commands failed", "cause", err)
 		return err
@@ -119,14 +197,20 @@ func push(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes, commands []
 	defer rows.Close()
 
 	for i := 0; rows.Next(); i++ {
-		var creationDate time.Time
+		var (
+			creationDate time.Time
+			position     eventstore.Position
+		)
 
-		if err = rows.Scan(&commands[i].id, &creationDate); err != nil {
+		if err = rows.Scan(&commands[i].id, &creationDate, &position); err != nil {
 			logger.ErrorContext(ctx, "scan of returned command metadata failed", "cause", err)
 			return fmt.Errorf("push failed: %w", err)
 		}
 		commands[i].SetCreationDate(creationDate)
 		commands[i].SetSequence(commands[i].sequence)
+		if positioned, ok := commands[i].Command.(eventstore.PositionedCommand); ok {
+			positioned.SetPosition(position)
+		}
 	}
 
 	if rows.Err() != nil {
@@ -138,7 +222,7 @@ func push(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes, commands []
 	actionBuilder.Write(pushActionsPrefix)
 	actionsArgs := actionValues(commands, &actionBuilder)
 
-	_, err = tx.Exec(ctx, actionBuilder.String(), actionsArgs...)
+	_, err = tx.Exec(ctx, store.qualify(actionBuilder.String()), actionsArgs...)
 	if err != nil {
 		logger.ErrorContext(ctx, "store actions failed", "cause", err)
 		return err
@@ -147,22 +231,28 @@ func push(ctx context.Context, tx pgx.Tx, indexes *aggregateIndexes, commands []
 	return nil
 }
 
-func prepareIndexes(aggregates []eventstore.Aggregate) *aggregateIndexes {
+func prepareIndexes(ctx context.Context, aggregates []eventstore.Aggregate) *aggregateIndexes {
 	indexes := &aggregateIndexes{
 		aggregates: make([]*aggregateIndex, 0, len(aggregates)),
 	}
 
 	for _, aggregate := range aggregates {
-		index := indexes.byAggregate(aggregate.ID())
+		tenant := eventstore.TenantOf(ctx, aggregate)
+		if conditional, ok := aggregate.(eventstore.ConditionalAggregate); ok && conditional.AppendCondition() != nil {
+			indexes.conditions = append(indexes.conditions, &appendCondition{
+				AppendCondition: conditional.AppendCondition(),
+				tenant:          tenant,
+			})
+		}
+
+		index := indexes.byAggregate(tenant, aggregate.ID())
 		if index != nil {
 			continue
 		}
 		index = &aggregateIndex{
-			aggregate: aggregate.ID(),
-		}
-		if sequence := aggregate.CurrentSequence(); sequence != nil {
-			index.shouldCheckSequence = true
-			index.expectedSequence = *sequence
+			tenant:      tenant,
+			aggregate:   aggregate.ID(),
+			expectation: eventstore.ExpectationOf(aggregate),
 		}
 		indexes.aggregates = append(indexes.aggregates, index)
 	}
@@ -172,18 +262,53 @@ func prepareIndexes(aggregates []eventstore.Aggregate) *aggregateIndexes {
 
 type aggregateIndexes struct {
 	aggregates []*aggregateIndex
+	conditions []*appendCondition
+	// inTxOffset is the in_tx_order of the first command
+	// it's used if multiple pushes share the same transaction
+	inTxOffset int
+	// hashChain is true if the hashes of the events are computed
+	hashChain bool
 }
 
 type aggregateIndex struct {
-	aggregate           eventstore.TextSubjects
-	index               uint32
-	shouldCheckSequence bool
-	expectedSequence    uint32
+	tenant      string
+	aggregate   eventstore.TextSubjects
+	index       uint32
+	expectation eventstore.Expectation
+	// skipped is true if all commands of the aggregate were deduplicated
+	skipped bool
+	// hash is the hash of the last event of the aggregate
+	hash []byte
 }
 
-func (indexes *aggregateIndexes) byAggregate(aggregate eventstore.TextSubjects) *aggregateIndex {
+// reset clears the state of a previous transaction attempt
+func (indexes *aggregateIndexes) reset() {
 	for _, index := range indexes.aggregates {
-		if !reflect.DeepEqual(index.aggregate, aggregate) {
+		index.index = 0
+		index.skipped = false
+		index.hash = nil
+	}
+}
+
+// skipDeduplicated marks the aggregates whose commands were all deduplicated
+// the sequence of these aggregates is not checked anymore
+// because the commands were already stored by a previous push
+func (indexes *aggregateIndexes) skipDeduplicated(commands, pending []*command) {
+	hasPending := make(map[*aggregateIndex]bool, len(indexes.aggregates))
+	for _, cmd := range commands {
+		hasPending[indexes.byAggregate(cmd.tenant, cmd.aggregate)] = false
+	}
+	for _, cmd := range pending {
+		hasPending[indexes.byAggregate(cmd.tenant, cmd.aggregate)] = true
+	}
+	for index, isPending := range hasPending {
+		index.skipped = !isPending
+	}
+}
+
+func (indexes *aggregateIndexes) byAggregate(tenant string, aggregate eventstore.TextSubjects) *aggregateIndex {
+	for _, index := range indexes.aggregates {
+		if index.tenant != tenant || !reflect.DeepEqual(index.aggregate, aggregate) {
 			continue
 		}
 		return index
@@ -191,20 +316,29 @@ func (indexes *aggregateIndexes) byAggregate(aggregate eventstore.TextSubjects)
 	return nil
 }
 
-func (indexes *aggregateIndexes) increment(aggregate eventstore.TextSubjects) uint32 {
-	index := indexes.byAggregate(aggregate)
+func (indexes *aggregateIndexes) increment(cmd *command) uint32 {
+	index := indexes.byAggregate(cmd.tenant, cmd.aggregate)
 	if index == nil {
-		panic(fmt.Sprintf("aggregate not prepared in indexes: %v", aggregate))
+		panic(fmt.Sprintf("aggregate not prepared in indexes: %q %v", cmd.tenant, cmd.aggregate))
 	}
 	index.index++
 	return index.index
 }
 
+// chain links the command to the last event of its aggregate
+// the command must be the next event of the aggregate
+func (indexes *aggregateIndexes) chain(cmd *command) {
+	index := indexes.byAggregate(cmd.tenant, cmd.aggregate)
+	cmd.previousHash = index.hash
+	cmd.hash = eventHash(cmd.previousHash, cmd.aggregate, cmd.Action(), cmd.Revision(), cmd.sequence, cmd.payloadHash, cmd.metadata)
+	index.hash = cmd.hash
+}
+
 func (indexes *aggregateIndexes) toAggregateArgs() []any {
-	args := make([]any, len(indexes.aggregates))
+	args := make([]any, 0, len(indexes.aggregates)*2)
 
-	for i, index := range indexes.aggregates {
-		args[i] = index.aggregate
+	for _, index := range indexes.aggregates {
+		args = append(args, index.tenant, index.aggregate)
 	}
 
 	return args
@@ -216,7 +350,7 @@ var (
 
 func (indexes *aggregateIndexes) currentSequencesClauses(builder *strings.Builder) {
 	for i := range indexes.aggregates {
-		builder.Write([]byte(`"aggregate" = $` + strconv.Itoa(i+1)))
+		builder.Write([]byte(`(tenant = $` + strconv.Itoa(i*2+1) + ` AND "aggregate" = $` + strconv.Itoa(i*2+2) + `)`))
 		if i+1 < len(indexes.aggregates) {
 			builder.Write(or)
 		}
@@ -230,67 +364,112 @@ var (
 	smallIntCast  = []byte("::INT2")
 	intCast       = []byte("::INT4")
 	jsonbCast     = []byte("::JSONB")
+	bytesCast     = []byte("::BYTES")
 )
 
+// eventColumnCasts are the casts of the values of a single event
+// in the order of the input columns of [pushEventsPrefix]
+var eventColumnCasts = [][]byte{
+	textArrayCast, // aggregate
+	textArrayCast, // action
+	smallIntCast,  // revision
+	jsonbCast,     // payload
+	intCast,       // sequence
+	intCast,       // in_tx_order
+	jsonbCast,     // metadata
+	textCast,      // idempotency_key
+	bytesCast,     // encrypted_payload
+	uuidCast,      // data_key_id
+	textCast,      // data_subject
+	bytesCast,     // payload_hash
+	bytesCast,     // hash
+	bytesCast,     // previous_hash
+	textCast,      // key_id
+	bytesCast,     // signature
+	textCast,      // codec
+	bytesCast,     // raw_payload
+	textCast,      // compression
+	textCast,      // payload_ref
+	textCast,      // tenant
+	bytesCast,     // payload_salt
+}
+
 func (indexes *aggregateIndexes) eventValues(commands []*command, builder *strings.Builder) []any {
 	var (
 		index = 0
-		args  = make([]any, 0, len(commands)*6)
+		args  = make([]any, 0, len(commands)*len(eventColumnCasts))
 	)
 
 	for i := 0; i < len(commands); i++ {
 		builder.WriteRune('(')
-
-		builder.WriteRune('$')
-		builder.Write([]byte(strconv.Itoa(index + 1)))
-		builder.Write(textArrayCast)
-		builder.WriteRune(',')
-
-		builder.WriteRune('$')
-		builder.Write([]byte(strconv.Itoa(index + 2)))
-		builder.Write(textArrayCast)
-		builder.WriteRune(',')
-
-		builder.WriteRune('$')
-		builder.Write([]byte(strconv.Itoa(index + 3)))
-		builder.Write(smallIntCast)
-		builder.WriteRune(',')
-
-		builder.WriteRune('$')
-		builder.Write([]byte(strconv.Itoa(index + 4)))
-		builder.Write(jsonbCast)
-		builder.WriteRune(',')
-
-		builder.WriteRune('$')
-		builder.Write([]byte(strconv.Itoa(index + 5)))
-		builder.Write(intCast)
-		builder.WriteRune(',')
-
-		builder.WriteRune('$')
-		builder.Write([]byte(strconv.Itoa(index + 6)))
-		builder.Write(intCast)
-
+		for column, cast := range eventColumnCasts {
+			if column > 0 {
+				builder.WriteRune(',')
+			}
+			builder.WriteRune('$')
+			builder.Write([]byte(strconv.Itoa(index + column + 1)))
+			builder.Write(cast)
+		}
 		builder.WriteRune(')')
 
 		if i+1 < len(commands) {
 			builder.WriteRune(',')
 		}
-		index += 6
+		index += len(eventColumnCasts)
 
-		commands[i].sequence = indexes.increment(commands[i].aggregate)
+		commands[i].sequence = indexes.increment(commands[i])
+		if indexes.hashChain {
+			indexes.chain(commands[i])
+		}
+		payload, rawPayload := commands[i].storedPayload(), []byte(nil)
+		if !isJSON(commands[i].codec) || commands[i].compressedPayload != nil {
+			payload, rawPayload = nil, payload
+		}
+		encryptedPayload := commands[i].encryptedPayload
+		if encryptedPayload != nil {
+			// the plain payload must not be stored
+			payload, rawPayload = nil, nil
+		}
+		if commands[i].payloadRef != "" {
+			payload, rawPayload, encryptedPayload = nil, nil, nil
+		}
 		args = append(args,
 			commands[i].aggregate,
 			commands[i].Action(),
 			commands[i].Revision(),
-			commands[i].payload,
+			payload,
 			commands[i].sequence,
-			i,
+			indexes.inTxOffset+i,
+			commands[i].metadata,
+			nullableText(commands[i].idempotencyKey),
+			encryptedPayload,
+			nullableText(commands[i].dataKeyID),
+			nullableText(commands[i].dataSubject),
+			commands[i].payloadHash,
+			commands[i].hash,
+			commands[i].previousHash,
+			nullableText(commands[i].keyID),
+			commands[i].signature,
+			codecColumn(commands[i].codec),
+			rawPayload,
+			nullableText(commands[i].compression()),
+			nullableText(commands[i].payloadRef),
+			commands[i].tenant,
+			commands[i].payloadSalt,
 		)
 	}
 
 	return args
 }
 
+// nullableText maps empty strings to NULL
+func nullableText(text string) any {
+	if text == "" {
+		return nil
+	}
+	return text
+}
+
 func actionValues(commands []*command, builder *strings.Builder) []any {
 	var (
 		index = 0
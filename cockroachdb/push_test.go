@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -22,14 +23,42 @@ func Benchmark_Push_ParallelDifferentAggregate(b *testing.B) {
 	})
 }
 
+func Benchmark_Push_LargePayload(b *testing.B) {
+	ctx := context.Background()
+	for _, bench := range compressionBenchStores() {
+		b.Run(bench.name, func(b *testing.B) {
+			if err := store.Before(ctx, b); err != nil {
+				b.Fatal("unable to execute store.Before: ", err)
+			}
+			b.ResetTimer()
+			for i := range b.N {
+				if err := bench.store.Push(ctx, largeDocumentAggregate(strconv.Itoa(i))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			reportStoredPayloadSize(ctx, b)
+		})
+	}
+}
+
 func Test_Push_Compliance(t *testing.T) {
 	eventstore.PushComplianceTests(context.Background(), t, store)
 }
 
+func Test_Idempotency_Compliance(t *testing.T) {
+	eventstore.IdempotencyComplianceTests(context.Background(), t, store)
+}
+
+func Test_Metadata_Compliance(t *testing.T) {
+	eventstore.MetadataComplianceTests(context.Background(), t, store)
+}
+
 func Test_indexes_eventValues(t *testing.T) {
 	type args struct {
 		aggregates []eventstore.TextSubjects
 		commands   []*command
+		inTxOffset int
 	}
 	type want struct {
 		values string
@@ -47,6 +76,53 @@ func Test_indexes_eventValues(t *testing.T) {
 				commands: []*command{
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
+						payload:   nil,
+						Command: &testCommand{
+							testAction: &testAction{
+								action:   eventstore.TextSubjects{"user", "1", "added"},
+								revision: 1,
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES)",
+				args: []any{
+					eventstore.TextSubjects{"user", "1"},
+					eventstore.TextSubjects{"user", "1", "added"},
+					uint16(1),
+					[]byte(nil),
+					uint32(1),
+					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
+				},
+			},
+		},
+		{
+			name: "1 command with in tx offset",
+			args: args{
+				aggregates: []eventstore.TextSubjects{{"user", "1"}},
+				commands: []*command{
+					{
+						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -56,9 +132,109 @@ func Test_indexes_eventValues(t *testing.T) {
 						},
 					},
 				},
+				inTxOffset: 3,
+			},
+			want: want{
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES)",
+				args: []any{
+					eventstore.TextSubjects{"user", "1"},
+					eventstore.TextSubjects{"user", "1", "added"},
+					uint16(1),
+					[]byte(nil),
+					uint32(1),
+					3,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
+				},
+			},
+		},
+		{
+			name: "1 encrypted command",
+			args: args{
+				aggregates: []eventstore.TextSubjects{{"user", "1"}},
+				commands: []*command{
+					{
+						aggregate:        eventstore.TextSubjects{"user", "1"},
+						codec:            eventstore.JSONCodec.Name(),
+						payload:          []byte(`{"firstName":"first name"}`),
+						dataSubject:      "1",
+						dataKeyID:        "key",
+						encryptedPayload: []byte("ciphertext"),
+						Command: &testCommand{
+							testAction: &testAction{
+								action:   eventstore.TextSubjects{"user", "1", "added"},
+								revision: 1,
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES)",
+				args: []any{
+					eventstore.TextSubjects{"user", "1"},
+					eventstore.TextSubjects{"user", "1", "added"},
+					uint16(1),
+					[]byte(nil),
+					uint32(1),
+					0,
+					[]byte(nil),
+					nil,
+					[]byte("ciphertext"),
+					"key",
+					"1",
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
+				},
+			},
+		},
+		{
+			name: "1 offloaded encrypted command",
+			args: args{
+				aggregates: []eventstore.TextSubjects{{"user", "1"}},
+				commands: []*command{
+					{
+						aggregate:        eventstore.TextSubjects{"user", "1"},
+						codec:            eventstore.JSONCodec.Name(),
+						payload:          []byte(`{"firstName":"first name"}`),
+						dataSubject:      "1",
+						dataKeyID:        "key",
+						encryptedPayload: []byte("ciphertext"),
+						payloadRef:       "sha256-ref",
+						Command: &testCommand{
+							testAction: &testAction{
+								action:   eventstore.TextSubjects{"user", "1", "added"},
+								revision: 1,
+							},
+						},
+					},
+				},
 			},
 			want: want{
-				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4)",
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES)",
 				args: []any{
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "added"},
@@ -66,6 +242,115 @@ func Test_indexes_eventValues(t *testing.T) {
 					[]byte(nil),
 					uint32(1),
 					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					"key",
+					"1",
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					"sha256-ref",
+					"",
+					[]byte(nil),
+				},
+			},
+		},
+		{
+			name: "1 command with binary codec",
+			args: args{
+				aggregates: []eventstore.TextSubjects{{"user", "1"}},
+				commands: []*command{
+					{
+						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     "binary",
+						payload:   []byte("binary"),
+						Command: &testCommand{
+							testAction: &testAction{
+								action:   eventstore.TextSubjects{"user", "1", "added"},
+								revision: 1,
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES)",
+				args: []any{
+					eventstore.TextSubjects{"user", "1"},
+					eventstore.TextSubjects{"user", "1", "added"},
+					uint16(1),
+					[]byte(nil),
+					uint32(1),
+					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					"binary",
+					[]byte("binary"),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
+				},
+			},
+		},
+		{
+			name: "1 compressed command",
+			args: args{
+				aggregates: []eventstore.TextSubjects{{"user", "1"}},
+				commands: []*command{
+					{
+						aggregate:         eventstore.TextSubjects{"user", "1"},
+						codec:             eventstore.JSONCodec.Name(),
+						payload:           []byte(`{"firstName":"first name"}`),
+						compressedPayload: []byte("compressed"),
+						Command: &testCommand{
+							testAction: &testAction{
+								action:   eventstore.TextSubjects{"user", "1", "added"},
+								revision: 1,
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES)",
+				args: []any{
+					eventstore.TextSubjects{"user", "1"},
+					eventstore.TextSubjects{"user", "1", "added"},
+					uint16(1),
+					[]byte(nil),
+					uint32(1),
+					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte("compressed"),
+					"gzip",
+					nil,
+					"",
+					[]byte(nil),
 				},
 			},
 		},
@@ -76,6 +361,7 @@ func Test_indexes_eventValues(t *testing.T) {
 				commands: []*command{
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -86,6 +372,7 @@ func Test_indexes_eventValues(t *testing.T) {
 					},
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -97,7 +384,7 @@ func Test_indexes_eventValues(t *testing.T) {
 				},
 			},
 			want: want{
-				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4),($7::TEXT[],$8::TEXT[],$9::INT2,$10::JSONB,$11::INT4,$12::INT4)",
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES),($23::TEXT[],$24::TEXT[],$25::INT2,$26::JSONB,$27::INT4,$28::INT4,$29::JSONB,$30::TEXT,$31::BYTES,$32::UUID,$33::TEXT,$34::BYTES,$35::BYTES,$36::BYTES,$37::TEXT,$38::BYTES,$39::TEXT,$40::BYTES,$41::TEXT,$42::TEXT,$43::TEXT,$44::BYTES)",
 				args: []any{
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "added"},
@@ -105,12 +392,44 @@ func Test_indexes_eventValues(t *testing.T) {
 					[]byte(nil),
 					uint32(1),
 					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "changed"},
 					uint16(1),
 					[]byte(nil),
 					uint32(2),
 					1,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 				},
 			},
 		},
@@ -124,6 +443,7 @@ func Test_indexes_eventValues(t *testing.T) {
 				commands: []*command{
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -134,6 +454,7 @@ func Test_indexes_eventValues(t *testing.T) {
 					},
 					{
 						aggregate: eventstore.TextSubjects{"user", "2"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -144,6 +465,7 @@ func Test_indexes_eventValues(t *testing.T) {
 					},
 					{
 						aggregate: eventstore.TextSubjects{"user", "2"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -155,7 +477,7 @@ func Test_indexes_eventValues(t *testing.T) {
 				},
 			},
 			want: want{
-				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4),($7::TEXT[],$8::TEXT[],$9::INT2,$10::JSONB,$11::INT4,$12::INT4),($13::TEXT[],$14::TEXT[],$15::INT2,$16::JSONB,$17::INT4,$18::INT4)",
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES),($23::TEXT[],$24::TEXT[],$25::INT2,$26::JSONB,$27::INT4,$28::INT4,$29::JSONB,$30::TEXT,$31::BYTES,$32::UUID,$33::TEXT,$34::BYTES,$35::BYTES,$36::BYTES,$37::TEXT,$38::BYTES,$39::TEXT,$40::BYTES,$41::TEXT,$42::TEXT,$43::TEXT,$44::BYTES),($45::TEXT[],$46::TEXT[],$47::INT2,$48::JSONB,$49::INT4,$50::INT4,$51::JSONB,$52::TEXT,$53::BYTES,$54::UUID,$55::TEXT,$56::BYTES,$57::BYTES,$58::BYTES,$59::TEXT,$60::BYTES,$61::TEXT,$62::BYTES,$63::TEXT,$64::TEXT,$65::TEXT,$66::BYTES)",
 				args: []any{
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "added"},
@@ -163,18 +485,66 @@ func Test_indexes_eventValues(t *testing.T) {
 					[]byte(nil),
 					uint32(1),
 					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 					eventstore.TextSubjects{"user", "2"},
 					eventstore.TextSubjects{"user", "2", "added"},
 					uint16(1),
 					[]byte(nil),
 					uint32(1),
 					1,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 					eventstore.TextSubjects{"user", "2"},
 					eventstore.TextSubjects{"user", "2", "changed"},
 					uint16(1),
 					[]byte(nil),
 					uint32(2),
 					2,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 				},
 			},
 		},
@@ -184,6 +554,7 @@ func Test_indexes_eventValues(t *testing.T) {
 			var builder strings.Builder
 			indexes := &aggregateIndexes{
 				aggregates: make([]*aggregateIndex, len(tt.args.aggregates)),
+				inTxOffset: tt.args.inTxOffset,
 			}
 			for idx, aggregate := range tt.args.aggregates {
 				indexes.aggregates[idx] = &aggregateIndex{
@@ -222,6 +593,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 				commands: []*command{
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -233,7 +605,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 				},
 			},
 			want: want{
-				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4)",
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES)",
 				args: []any{
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "added"},
@@ -241,6 +613,22 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 					[]byte(nil),
 					uint32(1),
 					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 				},
 			},
 		},
@@ -251,6 +639,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 				commands: []*command{
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -261,6 +650,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 					},
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -272,7 +662,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 				},
 			},
 			want: want{
-				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4),($7::TEXT[],$8::TEXT[],$9::INT2,$10::JSONB,$11::INT4,$12::INT4)",
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES),($23::TEXT[],$24::TEXT[],$25::INT2,$26::JSONB,$27::INT4,$28::INT4,$29::JSONB,$30::TEXT,$31::BYTES,$32::UUID,$33::TEXT,$34::BYTES,$35::BYTES,$36::BYTES,$37::TEXT,$38::BYTES,$39::TEXT,$40::BYTES,$41::TEXT,$42::TEXT,$43::TEXT,$44::BYTES)",
 				args: []any{
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "added"},
@@ -280,12 +670,44 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 					[]byte(nil),
 					uint32(1),
 					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "changed"},
 					uint16(1),
 					[]byte(nil),
 					uint32(2),
 					1,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 				},
 			},
 		},
@@ -299,6 +721,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 				commands: []*command{
 					{
 						aggregate: eventstore.TextSubjects{"user", "1"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -309,6 +732,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 					},
 					{
 						aggregate: eventstore.TextSubjects{"user", "2"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -319,6 +743,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 					},
 					{
 						aggregate: eventstore.TextSubjects{"user", "2"},
+						codec:     eventstore.JSONCodec.Name(),
 						payload:   nil,
 						Command: &testCommand{
 							testAction: &testAction{
@@ -330,7 +755,7 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 				},
 			},
 			want: want{
-				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4),($7::TEXT[],$8::TEXT[],$9::INT2,$10::JSONB,$11::INT4,$12::INT4),($13::TEXT[],$14::TEXT[],$15::INT2,$16::JSONB,$17::INT4,$18::INT4)",
+				values: "($1::TEXT[],$2::TEXT[],$3::INT2,$4::JSONB,$5::INT4,$6::INT4,$7::JSONB,$8::TEXT,$9::BYTES,$10::UUID,$11::TEXT,$12::BYTES,$13::BYTES,$14::BYTES,$15::TEXT,$16::BYTES,$17::TEXT,$18::BYTES,$19::TEXT,$20::TEXT,$21::TEXT,$22::BYTES),($23::TEXT[],$24::TEXT[],$25::INT2,$26::JSONB,$27::INT4,$28::INT4,$29::JSONB,$30::TEXT,$31::BYTES,$32::UUID,$33::TEXT,$34::BYTES,$35::BYTES,$36::BYTES,$37::TEXT,$38::BYTES,$39::TEXT,$40::BYTES,$41::TEXT,$42::TEXT,$43::TEXT,$44::BYTES),($45::TEXT[],$46::TEXT[],$47::INT2,$48::JSONB,$49::INT4,$50::INT4,$51::JSONB,$52::TEXT,$53::BYTES,$54::UUID,$55::TEXT,$56::BYTES,$57::BYTES,$58::BYTES,$59::TEXT,$60::BYTES,$61::TEXT,$62::BYTES,$63::TEXT,$64::TEXT,$65::TEXT,$66::BYTES)",
 				args: []any{
 					eventstore.TextSubjects{"user", "1"},
 					eventstore.TextSubjects{"user", "1", "added"},
@@ -338,18 +763,66 @@ func Benchmark_indexes_eventValues(b *testing.B) {
 					[]byte(nil),
 					uint32(1),
 					0,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 					eventstore.TextSubjects{"user", "2"},
 					eventstore.TextSubjects{"user", "2", "added"},
 					uint16(1),
 					[]byte(nil),
 					uint32(1),
 					1,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 					eventstore.TextSubjects{"user", "2"},
 					eventstore.TextSubjects{"user", "2", "changed"},
 					uint16(1),
 					[]byte(nil),
 					uint32(2),
 					2,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					[]byte(nil),
+					[]byte(nil),
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					[]byte(nil),
+					nil,
+					nil,
+					"",
+					[]byte(nil),
 				},
 			},
 		},
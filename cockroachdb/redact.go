@@ -0,0 +1,245 @@
+package cockroachdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	crdb "github.com/cockroachdb/cockroach-go/v2/crdb/crdbpgxv5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var (
+	// ErrEmptyRedaction is returned if a [Redaction] doesn't select any events
+	ErrEmptyRedaction = errors.New("redaction requires event ids or a filter")
+
+	// RedactionAggregate is the aggregate of the audit events recorded by [CockroachDB.RedactEvents]
+	RedactionAggregate = eventstore.TextSubjects{"eventstore", "redaction"}
+	// RedactedAction is the action of the audit events recorded by [CockroachDB.RedactEvents]
+	RedactedAction = eventstore.TextSubjects{"eventstore", "redaction", "redacted"}
+)
+
+// Redaction selects the events whose payloads are redacted
+// events matching either the ids or the filter are redacted.
+// Only events of [eventstore.TenantFromContext] are redacted
+// unless [eventstore.Filter.CrossTenant] is set.
+type Redaction struct {
+	// EventIDs are the [eventstore.Event.ID]s of the events to redact
+	EventIDs []string
+	// Filter selects the events to redact, [eventstore.Filter.Limit] is ignored
+	Filter *eventstore.Filter
+	// Payload replaces the payloads of the events
+	// nil removes the payloads
+	Payload any
+	// Reason is recorded in the audit event
+	Reason string
+	// Actor is recorded as [eventstore.Metadata.Creator] of the audit event
+	Actor string
+}
+
+// RedactedPayload is the payload of the audit event recorded by [CockroachDB.RedactEvents]
+type RedactedPayload struct {
+	EventIDs []string `json:"eventIds"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+var (
+	// the events are joined with themselves to return the payload ref before the update
+	redactPrefix = `UPDATE {{schema}}.events AS e SET payload = $1::JSONB, encrypted_payload = NULL, data_key_id = NULL, data_subject = NULL, codec = NULL, raw_payload = NULL, compression = NULL, payload_ref = NULL, payload_salt = NULL, redacted_at = now() FROM {{schema}}.events AS old WHERE old.tenant = e.tenant AND old.id = e.id AND `
+	redactIDs    = `e.id = ANY($2::UUID[])`
+	redactSuffix = ` RETURNING e.id, COALESCE(old.payload_ref, '')`
+)
+
+// RedactEvents replaces the payloads of the selected events.
+// The aggregate, action, sequence and position of the events are kept.
+// The salt of the payload hash of [WithHashChain] is deleted, the hash stays part of the chain.
+// An audit event of [RedactedAction] is stored for the tenant of ctx in the same transaction
+// if at least one event was redacted.
+// Offloaded payloads of [WithBlobStore] which are not referenced by other events
+// are recorded as orphaned and deleted by [CockroachDB.CollectBlobs].
+func (store *CockroachDB) RedactEvents(ctx context.Context, redaction *Redaction) (redacted []string, err error) {
+	stmt, args, err := redactStatement(redaction, eventstore.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	stmt = store.qualify(stmt)
+
+	conn, err := store.acquirePushConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	err = crdb.ExecuteTx(ctx, conn, pushTxOptions, func(tx pgx.Tx) (err error) {
+		var refs []string
+		if redacted, refs, err = redact(ctx, tx, stmt, args); err != nil || len(redacted) == 0 {
+			return err
+		}
+		if err = store.orphanBlobs(ctx, tx, refs); err != nil {
+			return err
+		}
+		return store.pushRedacted(ctx, tx, redaction, redacted)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return redacted, nil
+}
+
+// redact returns the ids of the redacted events and the refs of their offloaded payloads
+func redact(ctx context.Context, tx pgx.Tx, stmt string, args []any) (redacted, refs []string, err error) {
+	rows, err := tx.Query(ctx, stmt, args...)
+	if err != nil {
+		logger.ErrorContext(ctx, "redact events failed", "cause", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, ref string
+		if err = rows.Scan(&id, &ref); err != nil {
+			logger.ErrorContext(ctx, "scan of redacted events failed", "cause", err)
+			return nil, nil, err
+		}
+		redacted = append(redacted, id)
+		if ref != "" && !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "read redacted events failed", "cause", err)
+		return nil, nil, err
+	}
+
+	return redacted, refs, nil
+}
+
+// pushRedacted stores the audit event of the redaction
+func (store *CockroachDB) pushRedacted(ctx context.Context, tx pgx.Tx, redaction *Redaction, redacted []string) error {
+	aggregates := []eventstore.Aggregate{
+		&redactionAggregate{
+			command: &redactedCommand{
+				payload: &RedactedPayload{
+					EventIDs: redacted,
+					Reason:   redaction.Reason,
+				},
+				actor: redaction.Actor,
+			},
+		},
+	}
+
+	indexes := prepareIndexes(ctx, aggregates)
+	commands, close, err := store.commandsFromAggregates(ctx, aggregates)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	return store.pushInTx(ctx, tx, indexes, commands)
+}
+
+func redactStatement(redaction *Redaction, tenant string) (string, []any, error) {
+	hasFilter := redaction.Filter != nil && len(redaction.Filter.Queries) > 0
+	if len(redaction.EventIDs) == 0 && !hasFilter {
+		return "", nil, ErrEmptyRedaction
+	}
+
+	var payload []byte
+	if redaction.Payload != nil {
+		var err error
+		if payload, err = json.Marshal(redaction.Payload); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var (
+		builder strings.Builder
+		index   = 2
+		args    = []any{payload, redaction.EventIDs}
+	)
+
+	builder.WriteString(redactPrefix)
+	isScoped := redaction.Filter == nil || !redaction.Filter.CrossTenant
+	if isScoped {
+		args = append(args, tenantClause(&builder, &index, tenant)...)
+		builder.WriteString(" AND (")
+	}
+	builder.WriteString(redactIDs)
+	if hasFilter {
+		builder.WriteString(" OR (")
+		args = append(args, queriesToClause(&builder, &index, redaction.Filter.Queries)...)
+		builder.WriteRune(')')
+	}
+	if isScoped {
+		builder.WriteRune(')')
+	}
+	builder.WriteString(redactSuffix)
+
+	return builder.String(), args, nil
+}
+
+var _ eventstore.Aggregate = (*redactionAggregate)(nil)
+
+type redactionAggregate struct {
+	command *redactedCommand
+}
+
+// ID implements [eventstore.Aggregate]
+func (*redactionAggregate) ID() eventstore.TextSubjects {
+	return RedactionAggregate
+}
+
+// Commands implements [eventstore.Aggregate]
+func (a *redactionAggregate) Commands() []eventstore.Command {
+	return []eventstore.Command{a.command}
+}
+
+// CurrentSequence implements [eventstore.Aggregate]
+func (*redactionAggregate) CurrentSequence() *uint32 {
+	return nil
+}
+
+var (
+	_ eventstore.Command         = (*redactedCommand)(nil)
+	_ eventstore.CommandMetadata = (*redactedCommand)(nil)
+)
+
+type redactedCommand struct {
+	payload *RedactedPayload
+	actor   string
+}
+
+// Action implements [eventstore.Command]
+func (*redactedCommand) Action() eventstore.TextSubjects {
+	return RedactedAction
+}
+
+// Revision implements [eventstore.Command]
+func (*redactedCommand) Revision() uint16 {
+	return 1
+}
+
+// Payload implements [eventstore.Command]
+func (c *redactedCommand) Payload() any {
+	return c.payload
+}
+
+// SetSequence implements [eventstore.Command]
+func (*redactedCommand) SetSequence(uint32) {}
+
+// SetCreationDate implements [eventstore.Command]
+func (*redactedCommand) SetCreationDate(time.Time) {}
+
+// Metadata implements [eventstore.CommandMetadata]
+func (c *redactedCommand) Metadata() *eventstore.Metadata {
+	if c.actor == "" {
+		return nil
+	}
+	return &eventstore.Metadata{Creator: c.actor}
+}
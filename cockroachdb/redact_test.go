@@ -0,0 +1,191 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+func Test_RedactEvents(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+
+	err := store.Push(ctx, &testAggregate{
+		id: eventstore.TextSubjects{"user", "1"},
+		commands: []eventstore.Command{
+			&testCommand{
+				testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "added"}, revision: 1},
+				payload:    map[string]string{"name": "gigi"},
+			},
+			&testCommand{
+				testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "changed"}, revision: 1},
+				payload:    map[string]string{"name": "gigi"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	redacted, err := store.RedactEvents(ctx, &Redaction{
+		Filter: &eventstore.Filter{
+			Queries: []*eventstore.FilterQuery{
+				{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.TextSubject("1"), eventstore.TextSubject("added")}},
+			},
+		},
+		Payload: map[string]string{"name": "redacted"},
+		Reason:  "legal request",
+		Actor:   "admin",
+	})
+	if err != nil {
+		t.Fatalf("unable to redact events: %v", err)
+	}
+	if len(redacted) != 1 {
+		t.Fatalf("expected 1 redacted event, got: %v", redacted)
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+		},
+	}
+	var sequence uint32
+	for event, err := range store.FilterIter(ctx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		sequence++
+		if event.Sequence() != sequence {
+			t.Errorf("order must be kept, expected sequence %d, got: %d", sequence, event.Sequence())
+		}
+
+		payload := make(map[string]string)
+		if err = event.UnmarshalPayload(&payload); err != nil {
+			t.Fatalf("unable to unmarshal payload: %v", err)
+		}
+		isRedacted := event.ID() == redacted[0]
+		if isRedacted != !event.(eventstore.RedactedEvent).RedactedAt().IsZero() {
+			t.Errorf("unexpected redaction time of %v: %v", event.Action(), event.(eventstore.RedactedEvent).RedactedAt())
+		}
+		if want := map[string]string{"name": "gigi"}; isRedacted {
+			want["name"] = "redacted"
+			if !reflect.DeepEqual(payload, want) {
+				t.Errorf("unexpected payload want: %v, got: %v", want, payload)
+			}
+		}
+	}
+	if sequence != 2 {
+		t.Errorf("events must be kept, got %d events", sequence)
+	}
+
+	audit := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("eventstore"), eventstore.TextSubject("redaction"), eventstore.TextSubject("redacted")}},
+		},
+	}
+	var audited bool
+	for event, err := range store.FilterIter(ctx, audit) {
+		if err != nil {
+			t.Fatalf("unable to filter audit events: %v", err)
+		}
+		payload := new(RedactedPayload)
+		if err = event.UnmarshalPayload(payload); err != nil {
+			t.Fatalf("unable to unmarshal audit payload: %v", err)
+		}
+		want := &RedactedPayload{EventIDs: redacted, Reason: "legal request"}
+		if !reflect.DeepEqual(payload, want) {
+			t.Errorf("unexpected audit payload want: %+v, got: %+v", want, payload)
+		}
+		if event.Metadata() == nil || event.Metadata().Creator != "admin" {
+			t.Errorf("unexpected audit metadata: %+v", event.Metadata())
+		}
+		audited = true
+	}
+	if !audited {
+		t.Error("audit event must be stored")
+	}
+}
+
+func Test_redactStatement(t *testing.T) {
+	type want struct {
+		stmt string
+		args []any
+		err  error
+	}
+	tests := []struct {
+		name      string
+		redaction *Redaction
+		tenant    string
+		want      want
+	}{
+		{
+			name:      "empty",
+			redaction: &Redaction{Reason: "reason"},
+			want: want{
+				err: ErrEmptyRedaction,
+			},
+		},
+		{
+			name: "ids",
+			redaction: &Redaction{
+				EventIDs: []string{"1", "2"},
+			},
+			want: want{
+				stmt: `UPDATE {{schema}}.events AS e SET payload = $1::JSONB, encrypted_payload = NULL, data_key_id = NULL, data_subject = NULL, codec = NULL, raw_payload = NULL, compression = NULL, payload_ref = NULL, payload_salt = NULL, redacted_at = now() FROM {{schema}}.events AS old WHERE old.tenant = e.tenant AND old.id = e.id AND e.tenant = $3 AND (e.id = ANY($2::UUID[])) RETURNING e.id, COALESCE(old.payload_ref, '')`,
+				args: []any{[]byte(nil), []string{"1", "2"}, ""},
+			},
+		},
+		{
+			name: "filter with payload",
+			redaction: &Redaction{
+				Filter: &eventstore.Filter{
+					Queries: []*eventstore.FilterQuery{
+						{
+							Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.MultiToken},
+						},
+					},
+				},
+				Payload: map[string]string{"name": "redacted"},
+			},
+			tenant: "tenant",
+			want: want{
+				stmt: `UPDATE {{schema}}.events AS e SET payload = $1::JSONB, encrypted_payload = NULL, data_key_id = NULL, data_subject = NULL, codec = NULL, raw_payload = NULL, compression = NULL, payload_ref = NULL, payload_salt = NULL, redacted_at = now() FROM {{schema}}.events AS old WHERE old.tenant = e.tenant AND old.id = e.id AND e.tenant = $3 AND (e.id = ANY($2::UUID[]) OR ((e.id IN (SELECT a.event FROM {{schema}}.actions a WHERE a.action = $4 AND a.depth = $5) AND e.action_depth >= $6))) RETURNING e.id, COALESCE(old.payload_ref, '')`,
+				args: []any{
+					[]byte(`{"name":"redacted"}`), []string(nil),
+					"tenant",
+					eventstore.TextSubject("user"), 0, 2,
+				},
+			},
+		},
+		{
+			name: "cross tenant",
+			redaction: &Redaction{
+				EventIDs: []string{"1"},
+				Filter:   &eventstore.Filter{CrossTenant: true},
+			},
+			want: want{
+				stmt: `UPDATE {{schema}}.events AS e SET payload = $1::JSONB, encrypted_payload = NULL, data_key_id = NULL, data_subject = NULL, codec = NULL, raw_payload = NULL, compression = NULL, payload_ref = NULL, payload_salt = NULL, redacted_at = now() FROM {{schema}}.events AS old WHERE old.tenant = e.tenant AND old.id = e.id AND e.id = ANY($2::UUID[]) RETURNING e.id, COALESCE(old.payload_ref, '')`,
+				args: []any{[]byte(nil), []string{"1"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, args, err := redactStatement(tt.redaction, tt.tenant)
+			if !errors.Is(err, tt.want.err) {
+				t.Fatalf("unexpected error want: %v, got: %v", tt.want.err, err)
+			}
+			if stmt != tt.want.stmt {
+				t.Errorf("unexpected stmt want:\n%q\ngot:\n%q", tt.want.stmt, stmt)
+			}
+			if !reflect.DeepEqual(args, tt.want.args) {
+				t.Errorf("unexpected args want:\n%v\ngot:\n%v", tt.want.args, args)
+			}
+		})
+	}
+}
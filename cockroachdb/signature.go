@@ -0,0 +1,88 @@
+package cockroachdb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// encryptedCodecPrefix prefixes the codec in the signing message of encrypted payloads
+const encryptedCodecPrefix = "encrypted+"
+
+// signCommands signs the canonical form of the commands using signer
+// encrypted payloads are signed as stored, so the signature doesn't reveal the payload
+// after the data subject was forgotten
+func signCommands(ctx context.Context, signer eventstore.Signer, commands []*command) error {
+	for _, cmd := range commands {
+		// the signature is kept if the transaction is retried
+		// encrypted payloads change on each retry
+		if cmd.signature != nil && cmd.encryptedPayload == nil {
+			continue
+		}
+		codec, payload := cmd.codec, cmd.payload
+		if cmd.encryptedPayload != nil {
+			codec, payload = encryptedCodecPrefix+cmd.codec, cmd.encryptedPayload
+		}
+		message, err := eventstore.SigningMessage(cmd.aggregate, cmd.Action(), cmd.Revision(), codec, payload, cmd.metadata)
+		if err != nil {
+			logger.ErrorContext(ctx, "create signing message failed", "cause", err, "action", cmd.Action().Join("."))
+			return err
+		}
+		if cmd.signature, err = signer.Sign(message); err != nil {
+			logger.ErrorContext(ctx, "sign command failed", "cause", err, "action", cmd.Action().Join("."))
+			return err
+		}
+		cmd.keyID = signer.KeyID()
+	}
+	return nil
+}
+
+// KeyID implements [eventstore.SignedEvent]
+func (e *event) KeyID() string {
+	return e.keyID
+}
+
+// VerifySignature implements [eventstore.SignedEvent]
+// The signature of redacted events is invalid because the payload was replaced.
+// Encrypted payloads are verified as stored, also if the data subject was forgotten.
+func (e *event) VerifySignature(verifier eventstore.Verifier) (err error) {
+	if e.signature == nil {
+		return eventstore.ErrUnsigned
+	}
+
+	var (
+		codec   = e.codecName
+		payload []byte
+	)
+	if e.dataSubject != "" {
+		codec = encryptedCodecPrefix + e.codecName
+		payload, err = e.storedEncryptedPayload()
+	} else {
+		payload, err = e.plainPayload()
+	}
+	if err != nil {
+		return err
+	}
+
+	var metadata []byte
+	if e.metadata != nil {
+		if metadata, err = json.Marshal(e.metadata); err != nil {
+			return err
+		}
+	}
+
+	message, err := eventstore.SigningMessage(e.aggregate, e.action, e.revision, codec, payload, metadata)
+	if err != nil {
+		return err
+	}
+	return verifier.Verify(e.keyID, message, e.signature)
+}
+
+// storedEncryptedPayload returns the encrypted payload as stored, offloaded payloads are fetched
+func (e *event) storedEncryptedPayload() ([]byte, error) {
+	if e.payloadRef != "" {
+		return loadBlob(e.ctx, e.blobs, e.payloadRef)
+	}
+	return e.encryptedPayload, nil
+}
@@ -0,0 +1,175 @@
+package cockroachdb
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+func Test_SignedEvents(t *testing.T) {
+	ctx := context.Background()
+	if err := store.Before(ctx, t); err != nil {
+		t.Fatal("unable to execute store.Before: ", err)
+	}
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	signing := New(&Config{Pool: store.client}, WithSigner(eventstore.NewEd25519Signer("key-1", private)))
+
+	err = signing.Push(ctx, &testAggregate{
+		id: eventstore.TextSubjects{"user", "1"},
+		commands: []eventstore.Command{
+			&testCommand{
+				testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "added"}, revision: 1},
+				payload:    map[string]string{"name": "gigi"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.TextSubject("1"), eventstore.MultiToken}},
+		},
+	}
+	var verified bool
+	for event, err := range store.FilterIter(ctx, filter) {
+		if err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		signed := event.(eventstore.SignedEvent)
+		if signed.KeyID() != "key-1" {
+			t.Errorf("unexpected key id: %q", signed.KeyID())
+		}
+		if err = signed.VerifySignature(eventstore.Ed25519Verifier{"key-1": public}); err != nil {
+			t.Errorf("unable to verify signature: %v", err)
+		}
+		verified = true
+	}
+	if !verified {
+		t.Error("signed event must be stored")
+	}
+}
+
+func Test_event_VerifySignature(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	verifier := eventstore.Ed25519Verifier{"key-1": public}
+
+	signedEvent := func(payload []byte) *event {
+		cmd := &command{
+			Command: &testCommand{
+				testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "added"}, revision: 1},
+			},
+			aggregate: eventstore.TextSubjects{"user", "1"},
+			codec:     eventstore.JSONCodec.Name(),
+			payload:   payload,
+			metadata:  []byte(`{"creator":"gigi"}`),
+		}
+		if err := signCommands(context.Background(), eventstore.NewEd25519Signer("key-1", private), []*command{cmd}); err != nil {
+			t.Fatalf("unable to sign command: %v", err)
+		}
+		return &event{
+			aggregate: cmd.aggregate,
+			action:    cmd.Action(),
+			revision:  cmd.Revision(),
+			// JSONB doesn't keep the formatting
+			payload:   []byte(`{"name": "gigi"}`),
+			metadata:  &eventstore.Metadata{Creator: "gigi"},
+			codecName: cmd.codec,
+			codec:     eventstore.JSONCodec,
+			keyID:     cmd.keyID,
+			signature: cmd.signature,
+		}
+	}
+
+	encryptedEvent := func(encryptedPayload []byte) *event {
+		cmd := &command{
+			Command: &testCommand{
+				testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "added"}, revision: 1},
+			},
+			aggregate:        eventstore.TextSubjects{"user", "1"},
+			codec:            eventstore.JSONCodec.Name(),
+			payload:          []byte(`{"name":"gigi"}`),
+			encryptedPayload: encryptedPayload,
+			dataSubject:      "gigi",
+		}
+		if err := signCommands(context.Background(), eventstore.NewEd25519Signer("key-1", private), []*command{cmd}); err != nil {
+			t.Fatalf("unable to sign command: %v", err)
+		}
+		// the key of the data subject was deleted
+		return &event{
+			aggregate:        cmd.aggregate,
+			action:           cmd.Action(),
+			revision:         cmd.Revision(),
+			encryptedPayload: encryptedPayload,
+			dataSubject:      cmd.dataSubject,
+			codecName:        cmd.codec,
+			codec:            eventstore.JSONCodec,
+			keyID:            cmd.keyID,
+			signature:        cmd.signature,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		event func() *event
+		want  error
+	}{
+		{
+			name:  "valid",
+			event: func() *event { return signedEvent([]byte(`{"name":"gigi"}`)) },
+		},
+		{
+			name:  "unsigned",
+			event: func() *event { return &event{} },
+			want:  eventstore.ErrUnsigned,
+		},
+		{
+			name: "tampered payload",
+			event: func() *event {
+				e := signedEvent([]byte(`{"name":"gigi"}`))
+				e.payload = []byte(`{"name":"gugu"}`)
+				return e
+			},
+			want: eventstore.ErrInvalidSignature,
+		},
+		{
+			name: "tampered metadata",
+			event: func() *event {
+				e := signedEvent([]byte(`{"name":"gigi"}`))
+				e.metadata.Creator = "gugu"
+				return e
+			},
+			want: eventstore.ErrInvalidSignature,
+		},
+		{
+			name:  "forgotten",
+			event: func() *event { return encryptedEvent([]byte("encrypted")) },
+		},
+		{
+			name: "tampered encrypted payload",
+			event: func() *event {
+				e := encryptedEvent([]byte("encrypted"))
+				e.encryptedPayload = []byte("tampered")
+				return e
+			},
+			want: eventstore.ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.event().VerifySignature(verifier); !errors.Is(err, tt.want) {
+				t.Errorf("unexpected error want: %v, got: %v", tt.want, err)
+			}
+		})
+	}
+}
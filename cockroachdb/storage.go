@@ -4,7 +4,10 @@ import (
 	"context"
 	_ "embed"
 	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/adlerhurst/eventstore/v2"
@@ -13,8 +16,15 @@ import (
 
 type Config struct {
 	Pool *pgxpool.Pool
+	// Schema contains the tables of the store, default is [DefaultSchema].
+	// Stores of different schemas are independent of each other,
+	// e.g. to run an eventstore per bounded context in the same database.
+	Schema string
 }
 
+// DefaultSchema is the schema of the tables if [Config.Schema] is empty
+const DefaultSchema = "eventstore"
+
 var (
 	_           eventstore.Eventstore = (*CockroachDB)(nil)
 	logger                            = slog.Default()
@@ -23,9 +33,29 @@ var (
 )
 
 type CockroachDB struct {
-	client        *pgxpool.Pool
+	client *pgxpool.Pool
+	// schema is the sanitized [Config.Schema]
+	schema string
+	// schemaReplacer replaces [schemaPlaceholder] in the statements
+	schemaReplacer *strings.Replacer
+
 	pushAppName   string
 	filterAppName string
+
+	deduplicationWindow time.Duration
+	rejectDuplicates    bool
+	hashChain           bool
+	signer              eventstore.Signer
+	codec               eventstore.Codec
+	// codecs decode the payloads of events by the name of their codec
+	codecs map[string]eventstore.Codec
+
+	compressionThreshold int
+
+	blobs     eventstore.BlobStore
+	blobLimit int
+
+	retainEvents bool
 }
 
 func New(config *Config, opts ...storageOpt) *CockroachDB {
@@ -33,8 +63,22 @@ func New(config *Config, opts ...storageOpt) *CockroachDB {
 		client:        config.Pool,
 		pushAppName:   "es_push",
 		filterAppName: "es_filter",
+		codec:         eventstore.JSONCodec,
+		codecs: map[string]eventstore.Codec{
+			eventstore.JSONCodec.Name(): eventstore.JSONCodec,
+		},
+	}
+
+	store.schema = pgx.Identifier{DefaultSchema}.Sanitize()
+	if config.Schema != "" && config.Schema != DefaultSchema {
+		store.schema = pgx.Identifier{config.Schema}.Sanitize()
+		// open pushes of other schemas must not block filters of this store
+		store.pushAppName += "_" + config.Schema
+		store.filterAppName += "_" + config.Schema
 	}
 
+	store.schemaReplacer = strings.NewReplacer(schemaPlaceholder, store.schema)
+
 	for _, opt := range opts {
 		opt(store)
 	}
@@ -42,6 +86,14 @@ func New(config *Config, opts ...storageOpt) *CockroachDB {
 	return store
 }
 
+// schemaPlaceholder qualifies the tables in the statements, e.g. {{schema}}.events
+const schemaPlaceholder = "{{schema}}"
+
+// qualify replaces [schemaPlaceholder] in stmt with the schema of the store
+func (store *CockroachDB) qualify(stmt string) string {
+	return store.schemaReplacer.Replace(stmt)
+}
+
 type storageOpt func(*CockroachDB)
 
 func WithLogger(l *slog.Logger) storageOpt {
@@ -50,25 +102,194 @@ func WithLogger(l *slog.Logger) storageOpt {
 	}
 }
 
+// WithPushAppName sets the application name of push transactions,
+// default is "es_push" suffixed by "_" and [Config.Schema] if it differs from [DefaultSchema].
+// Filters wait for open transactions of this application name,
+// therefore stores of different schemas must use different names.
 func WithPushAppName(name string) storageOpt {
 	return func(store *CockroachDB) {
 		store.pushAppName = name
 	}
 }
 
+// WithFilterAppName sets the application name of filter transactions,
+// default is "es_filter" suffixed by "_" and [Config.Schema] if it differs from [DefaultSchema].
 func WithFilterAppName(name string) storageOpt {
 	return func(store *CockroachDB) {
 		store.filterAppName = name
 	}
 }
 
-//go:embed 0_setup.sql
-var setupStmt string
+// WithDeduplicationWindow limits the check of [eventstore.IdempotentCommand]s
+// to events created within the window. By default all events are checked.
+func WithDeduplicationWindow(window time.Duration) storageOpt {
+	return func(store *CockroachDB) {
+		store.deduplicationWindow = window
+	}
+}
+
+// WithRejectDuplicates returns [eventstore.ErrDuplicateCommand] if a command was already pushed
+// By default duplicates are skipped silently.
+func WithRejectDuplicates() storageOpt {
+	return func(store *CockroachDB) {
+		store.rejectDuplicates = true
+	}
+}
+
+// WithHashChain stores a hash of each event which includes the hash
+// of the previous event of the aggregate.
+// The chain is verified using [CockroachDB.VerifyHashChain].
+func WithHashChain() storageOpt {
+	return func(store *CockroachDB) {
+		store.hashChain = true
+	}
+}
+
+// WithSigner signs each pushed command using signer.
+// The signature and [eventstore.Signer.KeyID] are stored with the event
+// and verified using [eventstore.SignedEvent.VerifySignature].
+func WithSigner(signer eventstore.Signer) storageOpt {
+	return func(store *CockroachDB) {
+		store.signer = signer
+	}
+}
+
+// WithCodec encodes the payloads of pushed commands using codec.
+// Payloads of [eventstore.JSONCodec] are stored as JSONB, others as bytes.
+// By default payloads are encoded as json.
+func WithCodec(codec eventstore.Codec) storageOpt {
+	return func(store *CockroachDB) {
+		store.codec = codec
+		store.codecs[codec.Name()] = codec
+	}
+}
+
+// WithCodecs decodes the payloads of events which were encoded by codecs,
+// e.g. events stored before [WithCodec] was changed.
+// Payloads of unknown codecs return [eventstore.ErrUnknownCodec].
+func WithCodecs(codecs ...eventstore.Codec) storageOpt {
+	return func(store *CockroachDB) {
+		for _, codec := range codecs {
+			store.codecs[codec.Name()] = codec
+		}
+	}
+}
+
+// WithCompression compresses payloads using gzip
+// if their encoded size is at least threshold bytes.
+// Compressed payloads are stored as bytes and decompressed by [eventstore.Event.UnmarshalPayload].
+// Payloads are kept uncompressed if compression doesn't reduce their size.
+func WithCompression(threshold int) storageOpt {
+	return func(store *CockroachDB) {
+		store.compressionThreshold = threshold
+	}
+}
 
+// WithBlobStore offloads payloads of at least limit bytes to blobs.
+// The events store the content-addressed [eventstore.BlobKey] of the payload
+// which is fetched and verified by [eventstore.Event.UnmarshalPayload].
+// The size is measured after compression and encryption.
+// If limit is 0 payloads are only read from blobs.
+//
+// Blobs of redacted events are deleted by [CockroachDB.CollectBlobs]
+// once no event references them anymore, see [CockroachDB.RedactEvents].
+func WithBlobStore(blobs eventstore.BlobStore, limit int) storageOpt {
+	return func(store *CockroachDB) {
+		store.blobs = blobs
+		store.blobLimit = limit
+	}
+}
+
+// WithRetainedEvents disables the reuse of filtered events.
+// By default events are only valid until [eventstore.Reducer.Reduce] returns
+// or the iteration continues, reducers which keep references to events
+// must either set this option or use [eventstore.Clone].
+func WithRetainedEvents() storageOpt {
+	return func(store *CockroachDB) {
+		store.retainEvents = true
+	}
+}
+
+var (
+	//go:embed 0_setup.sql
+	setupStmt string
+	//go:embed 1_metadata.sql
+	metadataStmt string
+	//go:embed 2_idempotency.sql
+	idempotencyStmt string
+	//go:embed 3_unique_constraints.sql
+	uniqueConstraintsStmt string
+	//go:embed 4_data_keys.sql
+	dataKeysStmt string
+	//go:embed 5_redaction.sql
+	redactionStmt string
+	//go:embed 6_hash_chain.sql
+	hashChainStmt string
+	//go:embed 7_signatures.sql
+	signaturesStmt string
+	//go:embed 8_codecs.sql
+	codecsStmt string
+	//go:embed 9_compression.sql
+	compressionStmt string
+	//go:embed 10_payload_refs.sql
+	payloadRefsStmt string
+	//go:embed 11_tenants.sql
+	tenantsStmt string
+	//go:embed 12_tenant_events_key.sql
+	tenantEventsKeyStmt string
+	//go:embed 13_tenant_unique_constraints_key.sql
+	tenantUniqueConstraintsKeyStmt string
+	//go:embed 14_tenant_unique_constraints.sql
+	tenantUniqueConstraintsStmt string
+	//go:embed 15_payload_salt.sql
+	payloadSaltStmt string
+	//go:embed 16_payload_ref_index.sql
+	payloadRefIndexStmt string
+	//go:embed 17_orphaned_blobs.sql
+	orphanedBlobsStmt string
+	//go:embed 18_tenant_indexes.sql
+	tenantIndexesStmt string
+
+	// migrations are executed in order during [CockroachDB.Setup]
+	// each statement must be idempotent
+	migrations = []string{
+		setupStmt,
+		metadataStmt,
+		idempotencyStmt,
+		uniqueConstraintsStmt,
+		dataKeysStmt,
+		redactionStmt,
+		hashChainStmt,
+		signaturesStmt,
+		codecsStmt,
+		compressionStmt,
+		payloadRefsStmt,
+		tenantsStmt,
+		// primary keys can't be changed in the same transaction as other schema changes of the table
+		tenantEventsKeyStmt,
+		tenantUniqueConstraintsKeyStmt,
+		tenantUniqueConstraintsStmt,
+		payloadSaltStmt,
+		payloadRefIndexStmt,
+		orphanedBlobsStmt,
+		// replaces the indexes of 1_metadata.sql and 2_idempotency.sql
+		tenantIndexesStmt,
+	}
+)
+
+// Setup creates the schema and its tables or migrates them to the current version
 func (store *CockroachDB) Setup(ctx context.Context) error {
-	_, err := store.client.Exec(ctx, setupStmt)
-	logger.ErrorContext(ctx, "setup failed", "cause", err)
-	return err
+	if _, err := store.client.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+store.schema); err != nil {
+		logger.ErrorContext(ctx, "create schema failed", "cause", err)
+		return err
+	}
+	for _, migration := range migrations {
+		if _, err := store.client.Exec(ctx, store.qualify(migration)); err != nil {
+			logger.ErrorContext(ctx, "setup failed", "cause", err)
+			return err
+		}
+	}
+	return nil
 }
 
 // Ready implements [eventstore.Eventstore]
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -26,7 +27,7 @@ func (*testStorage) After(ctx context.Context, t testing.TB) error {
 
 // Before implements eventstore.TestEventstore
 func (s *testStorage) Before(ctx context.Context, t testing.TB) (err error) {
-	_, err = s.client.Exec(ctx, "TRUNCATE eventstore.events CASCADE")
+	_, err = s.client.Exec(ctx, s.qualify("TRUNCATE {{schema}}.events, {{schema}}.unique_constraints, {{schema}}.data_keys, {{schema}}.orphaned_blobs CASCADE"))
 	return err
 }
 
@@ -91,6 +92,28 @@ func (c *testCommand) SetSequence(sequence uint32) {
 	c.sequence = sequence
 }
 
+var _ eventstore.Aggregate = (*testAggregate)(nil)
+
+type testAggregate struct {
+	id       eventstore.TextSubjects
+	commands []eventstore.Command
+}
+
+// ID implements eventstore.Aggregate.
+func (a *testAggregate) ID() eventstore.TextSubjects {
+	return a.id
+}
+
+// Commands implements eventstore.Aggregate.
+func (a *testAggregate) Commands() []eventstore.Command {
+	return a.commands
+}
+
+// CurrentSequence implements eventstore.Aggregate.
+func (*testAggregate) CurrentSequence() *uint32 {
+	return nil
+}
+
 func connectToDB() *pgxpool.Pool {
 	if isCI := os.Getenv("CI"); isCI == "true" {
 		return connectToTestServer()
@@ -124,3 +147,114 @@ func connectToLocalhost() *pgxpool.Pool {
 
 	return pool
 }
+
+func Test_Schema(t *testing.T) {
+	ctx := context.Background()
+	other := &testStorage{CockroachDB: New(&Config{Pool: store.client, Schema: "eventstore_other"})}
+	if err := other.Setup(ctx); err != nil {
+		t.Fatalf("unable to setup store of other schema: %v", err)
+	}
+
+	t.Run("push compliance", func(t *testing.T) {
+		eventstore.PushComplianceTests(ctx, t, other)
+	})
+	t.Run("filter compliance", func(t *testing.T) {
+		eventstore.FilterComplianceTests(ctx, t, other)
+	})
+
+	for _, s := range []*testStorage{store, other} {
+		if err := s.Before(ctx, t); err != nil {
+			t.Fatal("unable to execute store.Before: ", err)
+		}
+	}
+
+	// the same aggregate is pushed to both stores
+	// the sequences are independent of each other
+	for _, s := range []*testStorage{store, other} {
+		err := s.Push(ctx, &testAggregate{
+			id: eventstore.TextSubjects{"user", "1"},
+			commands: []eventstore.Command{
+				&testCommand{
+					testAction: &testAction{action: eventstore.TextSubjects{"user", "1", "added"}, revision: 1},
+					payload:    map[string]string{"schema": s.schema},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unable to push to schema %s: %v", s.schema, err)
+		}
+	}
+
+	filter := &eventstore.Filter{
+		Queries: []*eventstore.FilterQuery{
+			{Subjects: []eventstore.Subject{eventstore.TextSubject("user"), eventstore.MultiToken}},
+		},
+	}
+	for _, s := range []*testStorage{store, other} {
+		var payloads []map[string]string
+		for event, err := range s.FilterIter(ctx, filter) {
+			if err != nil {
+				t.Fatalf("unable to filter schema %s: %v", s.schema, err)
+			}
+			if event.Sequence() != 1 {
+				t.Errorf("sequences of schema %s must be independent, got: %d", s.schema, event.Sequence())
+			}
+			payload := make(map[string]string)
+			if err = event.UnmarshalPayload(&payload); err != nil {
+				t.Fatalf("unable to unmarshal payload: %v", err)
+			}
+			payloads = append(payloads, payload)
+		}
+		if want := []map[string]string{{"schema": s.schema}}; !reflect.DeepEqual(payloads, want) {
+			t.Errorf("unexpected events of schema %s want: %v, got: %v", s.schema, want, payloads)
+		}
+	}
+}
+
+func Test_qualify(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		stmt   string
+		want   string
+	}{
+		{
+			name:   "empty",
+			schema: "",
+			stmt:   `SELECT e.id FROM {{schema}}.events e JOIN {{schema}}.actions a ON a.event = e.id`,
+			want:   `SELECT e.id FROM "eventstore".events e JOIN "eventstore".actions a ON a.event = e.id`,
+		},
+		{
+			name:   "default",
+			schema: DefaultSchema,
+			stmt:   `SELECT e.id FROM {{schema}}.events e JOIN {{schema}}.actions a ON a.event = e.id`,
+			want:   `SELECT e.id FROM "eventstore".events e JOIN "eventstore".actions a ON a.event = e.id`,
+		},
+		{
+			name:   "custom",
+			schema: "billing",
+			stmt:   `SELECT e.id FROM {{schema}}.events e JOIN {{schema}}.actions a ON a.event = e.id`,
+			want:   `SELECT e.id FROM "billing".events e JOIN "billing".actions a ON a.event = e.id`,
+		},
+		{
+			name:   "sanitized",
+			schema: `bill"ing`,
+			stmt:   `SELECT e.id FROM {{schema}}.events e JOIN {{schema}}.actions a ON a.event = e.id`,
+			want:   `SELECT e.id FROM "bill""ing".events e JOIN "bill""ing".actions a ON a.event = e.id`,
+		},
+		{
+			name:   "literals",
+			schema: "billing",
+			stmt:   `SELECT e.id FROM {{schema}}.events e WHERE e.action = ARRAY['eventstore.redaction', 'redacted'] AND e.metadata->>'source' = 'my_eventstore.events'`,
+			want:   `SELECT e.id FROM "billing".events e WHERE e.action = ARRAY['eventstore.redaction', 'redacted'] AND e.metadata->>'source' = 'my_eventstore.events'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := New(&Config{Schema: tt.schema})
+			if got := store.qualify(tt.stmt); got != tt.want {
+				t.Errorf("qualify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package cockroachdb
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+var (
+	addUniqueConstraintStmt    = `INSERT INTO {{schema}}.unique_constraints (tenant, namespace, "value") VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	removeUniqueConstraintStmt = `DELETE FROM {{schema}}.unique_constraints WHERE tenant = $1 AND namespace = $2 AND "value" = $3`
+)
+
+// uniqueConstraints applies the unique constraints of the commands in order
+// the values are unique per tenant of the commands
+// [eventstore.UniqueConstraintError] is returned for the first value which is already reserved
+func (store *CockroachDB) uniqueConstraints(ctx context.Context, tx pgx.Tx, commands []*command) (err error) {
+	var (
+		batch       pgx.Batch
+		constraints = make([]*eventstore.UniqueConstraint, 0, len(commands))
+
+		addStmt    = store.qualify(addUniqueConstraintStmt)
+		removeStmt = store.qualify(removeUniqueConstraintStmt)
+	)
+	for _, cmd := range commands {
+		for _, constraint := range cmd.uniqueConstraints {
+			switch constraint.Action {
+			case eventstore.UniqueConstraintAdd:
+				batch.Queue(addStmt, cmd.tenant, constraint.Namespace, constraint.Value)
+			case eventstore.UniqueConstraintRemove:
+				batch.Queue(removeStmt, cmd.tenant, constraint.Namespace, constraint.Value)
+			}
+			constraints = append(constraints, constraint)
+		}
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	results := tx.SendBatch(ctx, &batch)
+	defer results.Close()
+
+	for _, constraint := range constraints {
+		tag, err := results.Exec()
+		if err != nil {
+			logger.ErrorContext(ctx, "apply unique constraint failed", "cause", err, "namespace", constraint.Namespace)
+			return err
+		}
+		if constraint.Action == eventstore.UniqueConstraintAdd && tag.RowsAffected() == 0 {
+			logger.DebugContext(ctx, "unique constraint violated", "namespace", constraint.Namespace)
+			return &eventstore.UniqueConstraintError{
+				Namespace: constraint.Namespace,
+				Value:     constraint.Value,
+			}
+		}
+	}
+
+	return results.Close()
+}
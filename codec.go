@@ -0,0 +1,60 @@
+package eventstore
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrUnknownCodec is returned if the codec of a stored payload is not known
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Codec encodes the payloads of commands and decodes the payloads of events.
+// The [Codec.Name] is stored with each event so that events encoded
+// by different codecs can be decoded, e.g. during a migration from json to protobuf.
+// Besides [JSONCodec] codecs for protobuf, MessagePack and CBOR are provided
+// by the sub packages of codec, so the core doesn't depend on their libraries.
+type Codec interface {
+	// Name identifies the codec, it must not change
+	Name() string
+	// Marshal encodes the payload of a command
+	Marshal(object any) ([]byte, error)
+	// Unmarshal decodes the payload of an event into object
+	Unmarshal(data []byte, object any) error
+}
+
+// RawPayload returns the encoded payload of event and the [Codec.Name] it was encoded with.
+// If event doesn't implement [RawPayloadEvent] the payload is decoded into a [json.RawMessage].
+func RawPayload(event Event) (payload []byte, codec string, err error) {
+	if raw, ok := event.(RawPayloadEvent); ok {
+		payload, err = raw.RawPayload()
+		return payload, raw.PayloadCodec(), err
+	}
+
+	var message json.RawMessage
+	if err = event.UnmarshalPayload(&message); err != nil {
+		return nil, "", err
+	}
+	return message, JSONCodec.Name(), nil
+}
+
+var _ Codec = JSONCodec
+
+// JSONCodec encodes payloads using [encoding/json], it's the default codec
+var JSONCodec = jsonCodec{}
+
+type jsonCodec struct{}
+
+// Name implements [Codec]
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// Marshal implements [Codec]
+func (jsonCodec) Marshal(object any) ([]byte, error) {
+	return json.Marshal(object)
+}
+
+// Unmarshal implements [Codec]
+func (jsonCodec) Unmarshal(data []byte, object any) error {
+	return json.Unmarshal(data, object)
+}
@@ -0,0 +1,43 @@
+// Package cbor provides an [eventstore.Codec] which encodes payloads using CBOR (RFC 8949)
+package cbor
+
+import (
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// encMode encodes deterministically, equal payloads result in equal bytes
+var encMode = mustEncMode(cbor.CoreDetEncOptions())
+
+// mustEncMode panics if the options are invalid
+func mustEncMode(options cbor.EncOptions) cbor.EncMode {
+	mode, err := options.EncMode()
+	if err != nil {
+		panic("cbor: invalid encoding options: " + err.Error())
+	}
+	return mode
+}
+
+var _ eventstore.Codec = Codec
+
+// Codec encodes payloads using CBOR
+// the fields of structs are named by their cbor tag, or json tag if the field has no cbor tag
+var Codec = codec{}
+
+type codec struct{}
+
+// Name implements [eventstore.Codec]
+func (codec) Name() string {
+	return "cbor"
+}
+
+// Marshal implements [eventstore.Codec]
+func (codec) Marshal(object any) ([]byte, error) {
+	return encMode.Marshal(object)
+}
+
+// Unmarshal implements [eventstore.Codec]
+func (codec) Unmarshal(data []byte, object any) error {
+	return cbor.Unmarshal(data, object)
+}
@@ -0,0 +1,81 @@
+package cbor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+type testPayload struct {
+	Username string   `json:"username"`
+	Age      int      `json:"age,omitempty"`
+	Roles    []string `json:"roles"`
+}
+
+func TestCodec(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload any
+		target  func() any
+		want    any
+	}{
+		{
+			name:    "struct",
+			payload: &testPayload{Username: "gigi", Age: 42, Roles: []string{"admin"}},
+			target:  func() any { return new(testPayload) },
+			want:    &testPayload{Username: "gigi", Age: 42, Roles: []string{"admin"}},
+		},
+		{
+			name:    "json tags",
+			payload: &testPayload{Username: "gigi", Roles: []string{"admin"}},
+			target:  func() any { return new(map[string]any) },
+			want:    &map[string]any{"username": "gigi", "roles": []any{"admin"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Codec.Marshal(tt.payload)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			got := tt.target()
+			if err = Codec.Unmarshal(data, got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected payload want:\n%#v\ngot:\n%#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_mustEncMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   cbor.EncOptions
+		wantPanic bool
+	}{
+		{
+			name:    "valid",
+			options: cbor.CoreDetEncOptions(),
+		},
+		{
+			name:      "invalid",
+			options:   cbor.EncOptions{Sort: 100},
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recovered := recover(); (recovered != nil) != tt.wantPanic {
+					t.Errorf("unexpected panic want: %v, got: %v", tt.wantPanic, recovered)
+				}
+			}()
+			if mode := mustEncMode(tt.options); mode == nil {
+				t.Error("encoding mode must be set")
+			}
+		})
+	}
+}
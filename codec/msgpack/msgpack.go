@@ -0,0 +1,46 @@
+// Package msgpack provides an [eventstore.Codec] which encodes payloads using MessagePack
+package msgpack
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// jsonTag is used as fallback if a field has no msgpack tag
+// so that payloads encoded by [eventstore.JSONCodec] can be migrated without changes
+const jsonTag = "json"
+
+var _ eventstore.Codec = Codec
+
+// Codec encodes payloads using MessagePack
+// the fields of structs are named by their msgpack tag, or json tag if the field has no msgpack tag
+var Codec = codec{}
+
+type codec struct{}
+
+// Name implements [eventstore.Codec]
+func (codec) Name() string {
+	return "msgpack"
+}
+
+// Marshal implements [eventstore.Codec]
+func (codec) Marshal(object any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag(jsonTag)
+	encoder.SetSortMapKeys(true)
+	if err := encoder.Encode(object); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements [eventstore.Codec]
+func (codec) Unmarshal(data []byte, object any) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag(jsonTag)
+	return decoder.Decode(object)
+}
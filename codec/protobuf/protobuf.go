@@ -0,0 +1,45 @@
+// Package protobuf provides an [eventstore.Codec] which encodes payloads using protocol buffers
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/adlerhurst/eventstore/v2"
+)
+
+// ErrNoMessage is returned if the payload doesn't implement [proto.Message]
+var ErrNoMessage = errors.New("payload is not a proto message")
+
+var _ eventstore.Codec = Codec
+
+// Codec encodes payloads implementing [proto.Message] using the protobuf wire format
+var Codec = codec{}
+
+type codec struct{}
+
+// Name implements [eventstore.Codec]
+func (codec) Name() string {
+	return "protobuf"
+}
+
+// Marshal implements [eventstore.Codec]
+// the payload is encoded deterministically, equal messages result in equal payloads
+func (codec) Marshal(object any) ([]byte, error) {
+	message, ok := object.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNoMessage, object)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+// Unmarshal implements [eventstore.Codec]
+func (codec) Unmarshal(data []byte, object any) error {
+	message, ok := object.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrNoMessage, object)
+	}
+	return proto.Unmarshal(data, message)
+}
@@ -0,0 +1,37 @@
+package protobuf
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCodec(t *testing.T) {
+	payload, err := structpb.NewStruct(map[string]any{"username": "gigi", "age": 42})
+	if err != nil {
+		t.Fatalf("unable to create payload: %v", err)
+	}
+
+	data, err := Codec.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	got := new(structpb.Struct)
+	if err = Codec.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !proto.Equal(got, payload) {
+		t.Errorf("unexpected payload want: %v, got: %v", payload, got)
+	}
+}
+
+func TestCodec_noMessage(t *testing.T) {
+	if _, err := Codec.Marshal(map[string]any{"username": "gigi"}); !errors.Is(err, ErrNoMessage) {
+		t.Errorf("Marshal() expected %v, got: %v", ErrNoMessage, err)
+	}
+	if err := Codec.Unmarshal(nil, new(map[string]any)); !errors.Is(err, ErrNoMessage) {
+		t.Errorf("Unmarshal() expected %v, got: %v", ErrNoMessage, err)
+	}
+}
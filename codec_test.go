@@ -0,0 +1,63 @@
+package eventstore
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testRawPayloadEvent struct {
+	*testEvent
+	payload []byte
+}
+
+// RawPayload implements [RawPayloadEvent]
+func (e *testRawPayloadEvent) RawPayload() ([]byte, error) {
+	return e.payload, nil
+}
+
+// PayloadCodec implements [RawPayloadEvent]
+func (*testRawPayloadEvent) PayloadCodec() string {
+	return "binary"
+}
+
+func TestRawPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     Event
+		want      []byte
+		wantCodec string
+	}{
+		{
+			name: "raw payload event",
+			event: &testRawPayloadEvent{
+				testEvent: &testEvent{},
+				payload:   []byte("binary"),
+			},
+			want:      []byte("binary"),
+			wantCodec: "binary",
+		},
+		{
+			name: "decoded",
+			event: &testPayloadEvent{
+				testEvent: &testEvent{},
+				payload:   []byte(`{"name":"gigi"}`),
+			},
+			want:      []byte(`{"name":"gigi"}`),
+			wantCodec: JSONCodec.Name(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, codec, err := RawPayload(tt.event)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("unexpected payload want: %s, got: %s", tt.want, got)
+			}
+			if codec != tt.wantCodec {
+				t.Errorf("unexpected codec want: %q, got: %q", tt.wantCodec, codec)
+			}
+		})
+	}
+}
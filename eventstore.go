@@ -3,6 +3,8 @@ package eventstore
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -13,16 +15,21 @@ type Eventstore interface {
 	Ready(ctx context.Context) error
 	// Push stores the commands and sets the resulting metadata on the command
 	// the commands should be stored in a single transaction
-	// if the current sequence of an [AggregatePredefinedSequence] does not match
-	// [ErrSequenceNotMatched] is returned
+	// the [Metadata] of the commands is stamped with the values of [StampMetadata]
+	// the commands are stored for the tenant of [TenantOf] the aggregate
+	// if the [Expectation] of an aggregate is not met
+	// [SequenceNotMatchedError] is returned
+	// if the [AppendCondition] of a [ConditionalAggregate] is not met
+	// [ErrAppendConditionFailed] is returned
 	Push(ctx context.Context, aggregates ...Aggregate) error
 	// Filter applies the events matching the subjects on the reducer
+	// only events of [TenantFromContext] are applied unless [Filter.CrossTenant] is set
 	Filter(ctx context.Context, filter *Filter, reducer Reducer) error
 }
 
 // Aggregate represents the stream the events are written to
-// If the aggregate implements [AggregatePredefinedSequence],
-// the current sequence of the aggregate is verified from the storage
+// The current sequence of the aggregate is verified from the storage
+// based on [ExpectationOf] the aggregate
 type Aggregate interface {
 	// ID is the unique identifier of the stream
 	ID() TextSubjects
@@ -35,6 +42,24 @@ type Aggregate interface {
 	CurrentSequence() *uint32
 }
 
+// ConditionalAggregate can be implemented by an [Aggregate]
+// whose commands are based on a decision model built from a [Filter]
+type ConditionalAggregate interface {
+	// AppendCondition is checked in the same transaction as the commands are stored
+	// nil if the aggregate has no condition
+	AppendCondition() *AppendCondition
+}
+
+// AppendCondition fails the push with [ErrAppendConditionFailed]
+// if an event matching the filter was stored after the position
+type AppendCondition struct {
+	// Filter describes the events the decision was made on
+	// [Filter.Limit] is ignored
+	Filter *Filter
+	// After is the [Event.Position] of the last event the decision was made on
+	After Position
+}
+
 // Action describes the base data of [Command]'s and [Event]'s
 type Action interface {
 	// Action represent the change of an object
@@ -56,34 +81,240 @@ type Command interface {
 	// - nil (no payload),
 	// - struct which can be marshalled
 	// - pointer to struct which can be marshalled
+	// - [encoding/json.RawMessage] which is stored as is by json codecs
 	Payload() any
 
 	SetSequence(sequence uint32)
 	SetCreationDate(creationDate time.Time)
 }
 
+// PositionedCommand can be implemented by a [Command]
+// to receive the global position of the stored event.
+// The position can be used in [Filter.AwaitPosition] to read your own writes.
+type PositionedCommand interface {
+	SetPosition(position Position)
+}
+
+// CommandMetadata can be implemented by a [Command]
+// to store [Metadata] alongside the event
+type CommandMetadata interface {
+	// Metadata returns the metadata of the command
+	// nil if the command has no metadata
+	Metadata() *Metadata
+}
+
+// IdempotentCommand can be implemented by a [Command]
+// which must only be stored once, e.g. if a push is retried after a timeout
+type IdempotentCommand interface {
+	// IdempotencyKey identifies the command across retries
+	// an empty key disables deduplication of the command
+	IdempotencyKey() string
+	// SetDeduplicated is called if the command was not stored
+	// because an event with the same key was already stored.
+	// The sequence and creation date of the stored event are set on the command.
+	SetDeduplicated()
+}
+
+// UniqueConstraintCommand can be implemented by a [Command]
+// to reserve or release unique values across aggregates, e.g. usernames.
+// The values are unique per tenant, see [TenantOf].
+// The constraints are applied in the same transaction as the command is stored.
+type UniqueConstraintCommand interface {
+	// UniqueConstraints are applied in order
+	UniqueConstraints() []*UniqueConstraint
+}
+
+// DataSubjectCommand can be implemented by a [Command]
+// whose payload contains personal data of a data subject, e.g. a user.
+// The payload is encrypted with the key of the subject.
+// After the subject was forgotten using [Forgetter] the payload can't be read anymore,
+// but the event itself is kept.
+type DataSubjectCommand interface {
+	// DataSubject identifies the owner of the personal data
+	// an empty subject stores the payload unencrypted
+	DataSubject() string
+}
+
+// RawPayloadCommand can be implemented by a [Command] whose payload is already encoded,
+// e.g. if events are forwarded from another eventstore.
+// The payload is stored as is instead of encoding [Command.Payload].
+type RawPayloadCommand interface {
+	// RawPayload returns the encoded payload and the [Codec.Name] it was encoded with
+	// an empty codec means the payload is encoded by the codec of the eventstore
+	RawPayload() (payload []byte, codec string)
+}
+
+// Forgetter can be implemented by an [Eventstore] which supports crypto-shredding
+// of [DataSubjectCommand]s
+type Forgetter interface {
+	// ForgetSubject deletes the key of the data subject of [TenantFromContext]
+	// [Event.UnmarshalPayload] of the events of the subject returns [ForgottenError] afterwards
+	ForgetSubject(ctx context.Context, subject string) error
+}
+
+type UniqueConstraintAction uint8
+
+const (
+	// UniqueConstraintAdd reserves the value inside the namespace
+	// the push fails with [ErrUniqueConstraintViolated] if the value is already reserved
+	UniqueConstraintAdd UniqueConstraintAction = iota
+	// UniqueConstraintRemove releases the value inside the namespace
+	UniqueConstraintRemove
+)
+
+// UniqueConstraint is a value which must be unique inside the namespace
+type UniqueConstraint struct {
+	// Namespace groups the unique values, e.g. "username"
+	Namespace string
+	// Value is the unique value
+	Value  string
+	Action UniqueConstraintAction
+}
+
+// AddUniqueConstraint reserves the value inside the namespace
+func AddUniqueConstraint(namespace, value string) *UniqueConstraint {
+	return &UniqueConstraint{Namespace: namespace, Value: value, Action: UniqueConstraintAdd}
+}
+
+// RemoveUniqueConstraint releases the value inside the namespace
+func RemoveUniqueConstraint(namespace, value string) *UniqueConstraint {
+	return &UniqueConstraint{Namespace: namespace, Value: value, Action: UniqueConstraintRemove}
+}
+
+// UniqueConstraintError is returned if a value of a [UniqueConstraint] is already reserved
+// it matches [ErrUniqueConstraintViolated] using [errors.Is]
+type UniqueConstraintError struct {
+	Namespace string
+	Value     string
+}
+
+func (err *UniqueConstraintError) Error() string {
+	return fmt.Sprintf("%v: %q in %q", ErrUniqueConstraintViolated, err.Value, err.Namespace)
+}
+
+func (err *UniqueConstraintError) Is(target error) bool {
+	return target == ErrUniqueConstraintViolated
+}
+
+// ForgottenError is returned by [Event.UnmarshalPayload]
+// if the data subject of the payload was forgotten
+// it matches [ErrForgotten] using [errors.Is]
+type ForgottenError struct {
+	Subject string
+}
+
+func (err *ForgottenError) Error() string {
+	return fmt.Sprintf("%v: %q", ErrForgotten, err.Subject)
+}
+
+func (err *ForgottenError) Is(target error) bool {
+	return target == ErrForgotten
+}
+
+// SequenceConflict describes an aggregate whose current sequence did not match
+type SequenceConflict struct {
+	// Aggregate is the [Aggregate.ID]
+	Aggregate TextSubjects
+	// Expected is the expectation of the aggregate, see [ExpectationOf]
+	Expected Expectation
+	// Actual is the current sequence of the aggregate in the eventstore
+	Actual uint32
+}
+
+// SequenceNotMatchedError is returned by [Eventstore.Push]
+// if the current sequence of at least one aggregate did not match
+// it matches [ErrSequenceNotMatched] using [errors.Is]
+type SequenceNotMatchedError struct {
+	Conflicts []*SequenceConflict
+}
+
+func (err *SequenceNotMatchedError) Error() string {
+	var builder strings.Builder
+	builder.WriteString(ErrSequenceNotMatched.Error())
+	for i, conflict := range err.Conflicts {
+		if i == 0 {
+			builder.WriteString(": ")
+		} else {
+			builder.WriteString(", ")
+		}
+		fmt.Fprintf(&builder, "%s (expected %s, actual %d)", conflict.Aggregate.Join("."), conflict.Expected, conflict.Actual)
+	}
+	return builder.String()
+}
+
+func (err *SequenceNotMatchedError) Is(target error) bool {
+	return target == ErrSequenceNotMatched
+}
+
+// Metadata describes the context in which a command was created
+type Metadata struct {
+	// CorrelationID groups all events belonging to the same business flow
+	CorrelationID string `json:"correlationId,omitempty"`
+	// CausationID is the id of the event which caused the command
+	CausationID string `json:"causationId,omitempty"`
+	// Creator is the actor who created the command
+	Creator string `json:"creator,omitempty"`
+	// Headers are arbitrary key-value pairs
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
 // Event is the abstraction if a user wants to get events mapped by the eventstore
 type Event interface {
 	Action
+	// ID is the unique identifier of the event
+	ID() string
 	// Aggregate represents the object the command belongs to
 	// and is used to generate the `Sequence` of the [Event]
 	// e.g. user A: {"users", "A"}
 	Aggregate() TextSubjects
 	// Sequence represents the position of the event inside a specific subject
 	Sequence() uint32
+	// Position is the global position of the event in the eventstore
+	// events stored in the same transaction share the same position
+	Position() Position
 	// CreationDate is the timestamp the event was stored to the eventstore
 	CreationDate() time.Time
+	// Metadata is the metadata stored with the event
+	// nil if the command had no metadata
+	Metadata() *Metadata
 	// UnmarshalPayload maps the stored payload into the given object
 	// object must be of type *struct
 	UnmarshalPayload(object any) error
 }
 
+// RedactedEvent can be implemented by an [Event]
+// whose payload was redacted after it was stored
+type RedactedEvent interface {
+	// RedactedAt is the time the payload was redacted
+	// zero if the payload was not redacted
+	RedactedAt() time.Time
+}
+
+// RawPayloadEvent can be implemented by an [Event]
+// to read the encoded payload without decoding it, e.g. to forward events.
+// Use [RawPayload] to read the encoded payload of any [Event].
+type RawPayloadEvent interface {
+	// RawPayload returns the payload as encoded by its codec
+	// nil if the event has no payload
+	RawPayload() ([]byte, error)
+	// PayloadCodec is the [Codec.Name] of the payload
+	PayloadCodec() string
+}
+
 // Filter represents a query
 type Filter struct {
 	// Queries are queries on subjects
 	Queries []*FilterQuery
 	// Limit represents the maximum events returned
 	Limit uint64
+	// AwaitPosition blocks the filter until all events
+	// up to the position are visible or the context is done
+	// an empty position doesn't wait
+	AwaitPosition Position
+	// CrossTenant returns the events of all tenants
+	// instead of the tenant of [TenantFromContext].
+	// It's meant for administrative filters, e.g. projections over all tenants.
+	CrossTenant bool
 }
 
 type FilterQuery struct {
@@ -91,6 +322,8 @@ type FilterQuery struct {
 	Sequence SequenceFilter
 	// CreatedAt filters the time and event was created
 	CreatedAt CreatedAtFilter
+	// CorrelationID filters the events of the given [Metadata.CorrelationID]
+	CorrelationID string
 	// Action represents the event type
 	Subjects []Subject
 }
@@ -108,9 +341,53 @@ type CreatedAtFilter struct {
 // Reducer represents a model
 type Reducer interface {
 	// Reduce maps events to a model
+	// The events might be reused by the eventstore after Reduce returns,
+	// use [Clone] to keep references to them.
 	Reduce(events ...Event) error
 }
 
+// ContextReducer can be implemented by a [Reducer]
+// to receive the context of [Eventstore.Filter],
+// e.g. to push follow-up commands caused by the events, see [Router.HandleContext].
+type ContextReducer interface {
+	// ReduceContext is called instead of [Reducer.Reduce]
+	ReduceContext(ctx context.Context, events ...Event) error
+}
+
+// Reduce passes the events to the reducer
+// using [ContextReducer.ReduceContext] if it's implemented.
+// It's meant to be used by [Eventstore] implementations.
+func Reduce(ctx context.Context, reducer Reducer, events ...Event) error {
+	if contextReducer, ok := reducer.(ContextReducer); ok {
+		return contextReducer.ReduceContext(ctx, events...)
+	}
+	return reducer.Reduce(events...)
+}
+
+// CloneableEvent can be implemented by an [Event]
+// which is reused by the eventstore after it was reduced or yielded.
+type CloneableEvent interface {
+	// Clone returns a copy of the event which is not reused
+	Clone() Event
+}
+
+// Clone returns a copy of event which stays valid after [Reducer.Reduce] returns
+// or the iteration of [FilterIterator.FilterIter] continues.
+// Events which don't implement [CloneableEvent] are not reused and returned as is.
+func Clone(event Event) Event {
+	if cloneable, ok := event.(CloneableEvent); ok {
+		return cloneable.Clone()
+	}
+	return event
+}
+
 var (
+	// ErrSequenceNotMatched is matched by [SequenceNotMatchedError]
 	ErrSequenceNotMatched = errors.New("sequence of aggregate did not match")
+	ErrDuplicateCommand   = errors.New("command with the same idempotency key was already pushed")
+	// ErrUniqueConstraintViolated is matched by [UniqueConstraintError]
+	ErrUniqueConstraintViolated = errors.New("unique constraint violated")
+	ErrAppendConditionFailed    = errors.New("event matching the append condition was stored")
+	// ErrForgotten is matched by [ForgottenError]
+	ErrForgotten = errors.New("data subject was forgotten")
 )
@@ -0,0 +1,87 @@
+package eventstore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSequenceNotMatchedError(t *testing.T) {
+	err := error(&SequenceNotMatchedError{
+		Conflicts: []*SequenceConflict{
+			{Aggregate: TextSubjects{"user", "1"}, Expected: ExpectExact(1), Actual: 2},
+			{Aggregate: TextSubjects{"user", "2"}, Expected: ExpectNoStream, Actual: 5},
+		},
+	})
+
+	if !errors.Is(err, ErrSequenceNotMatched) {
+		t.Errorf("expected %v to match %v", err, ErrSequenceNotMatched)
+	}
+
+	want := "sequence of aggregate did not match: user.1 (expected 1, actual 2), user.2 (expected no stream, actual 5)"
+	if got := err.Error(); got != want {
+		t.Errorf("unexpected message want:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+type testCloneableEvent struct {
+	*testEvent
+	payload []byte
+}
+
+// Clone implements [CloneableEvent]
+func (e *testCloneableEvent) Clone() Event {
+	return &testCloneableEvent{testEvent: e.testEvent, payload: bytes.Clone(e.payload)}
+}
+
+func TestClone(t *testing.T) {
+	pooled := &testCloneableEvent{testEvent: &testEvent{}, payload: []byte("payload")}
+	unpooled := &testEvent{}
+
+	tests := []struct {
+		name  string
+		event Event
+		check func(t *testing.T, clone Event)
+	}{
+		{
+			name:  "cloneable",
+			event: pooled,
+			check: func(t *testing.T, clone Event) {
+				if clone == Event(pooled) {
+					t.Error("expected a copy")
+				}
+				pooled.payload[0] = 'P'
+				if string(clone.(*testCloneableEvent).payload) != "payload" {
+					t.Errorf("clone must not share the payload: %s", clone.(*testCloneableEvent).payload)
+				}
+			},
+		},
+		{
+			name:  "not cloneable",
+			event: unpooled,
+			check: func(t *testing.T, clone Event) {
+				if clone != Event(unpooled) {
+					t.Error("expected the event itself")
+				}
+			},
+		},
+		{
+			name:  "redacted",
+			event: &redactedEvent{Event: &testCloneableEvent{testEvent: &testEvent{}, payload: []byte("payload")}},
+			check: func(t *testing.T, clone Event) {
+				redacted, ok := clone.(*redactedEvent)
+				if !ok {
+					t.Fatalf("clone must stay redacted: %T", clone)
+				}
+				if _, ok = redacted.Event.(*testCloneableEvent); !ok {
+					t.Errorf("unexpected wrapped event: %T", redacted.Event)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, Clone(tt.event))
+		})
+	}
+}
@@ -20,12 +20,14 @@ type testUserReducer struct {
 	LastName  string `json:"lastName"`
 	Username  string `json:"username"`
 	isRemoved bool
+	metadata  *Metadata
 }
 
 // Reduce implements Reducer.
 func (r *testUserReducer) Reduce(events ...Event) error {
 	for _, event := range events {
 		r.sequence = event.Sequence()
+		r.metadata = event.Metadata()
 
 		if event.Action().Compare(TextSubject("user"), TextSubject(r.id), TextSubject("removed")) {
 			r.isRemoved = true
@@ -40,12 +42,23 @@ func (r *testUserReducer) Reduce(events ...Event) error {
 	return nil
 }
 
-var _ Aggregate = (*testUser)(nil)
+var (
+	_ Aggregate            = (*testUser)(nil)
+	_ ConditionalAggregate = (*testUser)(nil)
+	_ TenantAggregate      = (*testUser)(nil)
+)
 
 type testUser struct {
 	id                 string
 	currentSequence    uint32
 	predefinedSequence *uint32
+	metadata           *Metadata
+	idempotencyKey     string
+	uniqueConstraints  []*UniqueConstraint
+	appendCondition    *AppendCondition
+	expectation        *Expectation
+	dataSubject        string
+	tenant             string
 	commands           []Command
 }
 
@@ -64,6 +77,29 @@ func (a *testUser) ID() TextSubjects {
 	return []TextSubject{"user", TextSubject(a.id)}
 }
 
+// AppendCondition implements ConditionalAggregate.
+func (a *testUser) AppendCondition() *AppendCondition {
+	return a.appendCondition
+}
+
+// Tenant implements TenantAggregate.
+func (a *testUser) Tenant() string {
+	return a.tenant
+}
+
+var _ AggregateExpectation = (*expectingTestUser)(nil)
+
+// expectingTestUser is used if the aggregate defines an [Expectation]
+// otherwise the expectation is derived from [Aggregate.CurrentSequence]
+type expectingTestUser struct {
+	*testUser
+}
+
+// Expectation implements AggregateExpectation.
+func (a *expectingTestUser) Expectation() Expectation {
+	return *a.expectation
+}
+
 type testUserOpt func(*testUser) *testUser
 
 func newTestUser(id string, opts ...testUserOpt) Aggregate {
@@ -73,9 +109,28 @@ func newTestUser(id string, opts ...testUserOpt) Aggregate {
 		tu = opt(tu)
 	}
 
+	if tu.expectation != nil {
+		return &expectingTestUser{testUser: tu}
+	}
 	return tu
 }
 
+func withExpectation(expectation Expectation) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.expectation = &expectation
+		return tu
+	}
+}
+
+// withExistingEvents sets the amount of events already stored for the aggregate
+// it must be set before the commands are added
+func withExistingEvents(count uint32) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.currentSequence = count
+		return tu
+	}
+}
+
 func withPredefinedSequence(sequence uint32) testUserOpt {
 	return func(tu *testUser) *testUser {
 		tu.predefinedSequence = &sequence
@@ -83,15 +138,69 @@ func withPredefinedSequence(sequence uint32) testUserOpt {
 	}
 }
 
+func withAppendCondition(condition *AppendCondition) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.appendCondition = condition
+		return tu
+	}
+}
+
+// withMetadata sets the metadata of the added command
+// it must be set before [withAdded]
+func withMetadata(metadata *Metadata) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.metadata = metadata
+		return tu
+	}
+}
+
+// withIdempotencyKey sets the idempotency key of the added command
+// it must be set before [withAdded]
+func withIdempotencyKey(key string) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.idempotencyKey = key
+		return tu
+	}
+}
+
+// withUniqueConstraints sets the unique constraints of the added command
+// it must be set before [withAdded]
+func withUniqueConstraints(constraints ...*UniqueConstraint) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.uniqueConstraints = constraints
+		return tu
+	}
+}
+
+func withAggregateTenant(tenant string) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.tenant = tenant
+		return tu
+	}
+}
+
+// withDataSubject sets the data subject of the added command
+// it must be set before [withAdded]
+func withDataSubject(subject string) testUserOpt {
+	return func(tu *testUser) *testUser {
+		tu.dataSubject = subject
+		return tu
+	}
+}
+
 func withAdded(firstName, lastName, username string) testUserOpt {
 	return func(tu *testUser) *testUser {
 		tu.currentSequence++
 		tu.commands = append(tu.commands, &testUserAdded{
-			id:           tu.id,
-			FirstName:    firstName,
-			LastName:     lastName,
-			Username:     username,
-			wantSequence: tu.currentSequence,
+			id:                tu.id,
+			FirstName:         firstName,
+			LastName:          lastName,
+			Username:          username,
+			metadata:          tu.metadata,
+			idempotencyKey:    tu.idempotencyKey,
+			uniqueConstraints: tu.uniqueConstraints,
+			dataSubject:       tu.dataSubject,
+			wantSequence:      tu.currentSequence,
 		})
 		return tu
 	}
@@ -148,18 +257,36 @@ func withRemoved() testUserOpt {
 	}
 }
 
-var _ Command = (*testUserAdded)(nil)
+var (
+	_ Command           = (*testUserAdded)(nil)
+	_ CommandMetadata   = (*testUserAdded)(nil)
+	_ IdempotentCommand = (*testUserAdded)(nil)
+
+	_ UniqueConstraintCommand = (*testUserAdded)(nil)
+	_ PositionedCommand       = (*testUserAdded)(nil)
+	_ DataSubjectCommand      = (*testUserAdded)(nil)
+)
 
 type testUserAdded struct {
 	id        string
 	FirstName string `json:"firstName,omitempty"`
 	LastName  string `json:"lastName,omitempty"`
 	Username  string `json:"username,omitempty"`
+	metadata  *Metadata
 	// the following fields are used for assertion
 	wantSequence uint32
 
 	sequence  uint32
 	createdAt time.Time
+
+	idempotencyKey string
+	deduplicated   bool
+
+	uniqueConstraints []*UniqueConstraint
+
+	position Position
+
+	dataSubject string
 }
 
 // SetCreationDate implements [Command].
@@ -183,6 +310,24 @@ func (*testUserAdded) Revision() uint16 { return 1 }
 // Payload implements [Command]
 func (e *testUserAdded) Payload() interface{} { return e }
 
+// Metadata implements [CommandMetadata]
+func (e *testUserAdded) Metadata() *Metadata { return e.metadata }
+
+// IdempotencyKey implements [IdempotentCommand]
+func (e *testUserAdded) IdempotencyKey() string { return e.idempotencyKey }
+
+// SetDeduplicated implements [IdempotentCommand]
+func (e *testUserAdded) SetDeduplicated() { e.deduplicated = true }
+
+// UniqueConstraints implements [UniqueConstraintCommand]
+func (e *testUserAdded) UniqueConstraints() []*UniqueConstraint { return e.uniqueConstraints }
+
+// SetPosition implements [PositionedCommand]
+func (e *testUserAdded) SetPosition(position Position) { e.position = position }
+
+// DataSubject implements [DataSubjectCommand]
+func (e *testUserAdded) DataSubject() string { return e.dataSubject }
+
 func (c *testUserAdded) assert(t *testing.T) (failed bool) {
 	t.Helper()
 
@@ -381,9 +526,12 @@ type TestEventstore interface {
 
 func PushComplianceTests(ctx context.Context, t *testing.T, store TestEventstore) {
 	tests := []struct {
-		name        string
-		aggregates  []Aggregate
-		expectedErr error
+		name string
+		// existing aggregates are pushed before the aggregates
+		existing          []Aggregate
+		aggregates        []Aggregate
+		expectedErr       error
+		expectedConflicts []*SequenceConflict
 	}{
 		{
 			name: "multiple events",
@@ -417,6 +565,28 @@ func PushComplianceTests(ctx context.Context, t *testing.T, store TestEventstore
 			},
 			expectedErr: ErrSequenceNotMatched,
 		},
+		{
+			name: "multiple aggregates defined sequence error",
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withPredefinedSequence(1),
+					withAdded("first name", "last name", "username"),
+				),
+				newTestUser("2",
+					withPredefinedSequence(0),
+					withAdded("first name", "last name", "username"),
+				),
+				newTestUser("3",
+					withPredefinedSequence(3),
+					withAdded("first name", "last name", "username"),
+				),
+			},
+			expectedErr: ErrSequenceNotMatched,
+			expectedConflicts: []*SequenceConflict{
+				{Aggregate: TextSubjects{"user", "id"}, Expected: ExpectExact(1), Actual: 0},
+				{Aggregate: TextSubjects{"user", "3"}, Expected: ExpectExact(3), Actual: 0},
+			},
+		},
 		{
 			name: "multiple aggregates",
 			aggregates: []Aggregate{
@@ -433,16 +603,161 @@ func PushComplianceTests(ctx context.Context, t *testing.T, store TestEventstore
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "expect any",
+			existing: []Aggregate{
+				newTestUser("id", withAdded("first name", "last name", "username")),
+			},
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withExpectation(ExpectAny),
+					withExistingEvents(1),
+					withRemoved(),
+				),
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "expect no stream",
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withExpectation(ExpectNoStream),
+					withAdded("first name", "last name", "username"),
+				),
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "expect no stream error",
+			existing: []Aggregate{
+				newTestUser("id", withAdded("first name", "last name", "username")),
+			},
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withExpectation(ExpectNoStream),
+					withAdded("first name", "last name", "username"),
+				),
+			},
+			expectedErr: ErrSequenceNotMatched,
+			expectedConflicts: []*SequenceConflict{
+				{Aggregate: TextSubjects{"user", "id"}, Expected: ExpectNoStream, Actual: 1},
+			},
+		},
+		{
+			name: "expect stream exists",
+			existing: []Aggregate{
+				newTestUser("id", withAdded("first name", "last name", "username")),
+			},
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withExpectation(ExpectStreamExists),
+					withExistingEvents(1),
+					withRemoved(),
+				),
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "expect stream exists error",
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withExpectation(ExpectStreamExists),
+					withRemoved(),
+				),
+			},
+			expectedErr: ErrSequenceNotMatched,
+			expectedConflicts: []*SequenceConflict{
+				{Aggregate: TextSubjects{"user", "id"}, Expected: ExpectStreamExists, Actual: 0},
+			},
+		},
+		{
+			name: "expect exact",
+			existing: []Aggregate{
+				newTestUser("id", withAdded("first name", "last name", "username")),
+			},
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withExpectation(ExpectExact(1)),
+					withExistingEvents(1),
+					withRemoved(),
+				),
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "expect exact error",
+			existing: []Aggregate{
+				newTestUser("id", withAdded("first name", "last name", "username")),
+			},
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withExpectation(ExpectExact(2)),
+					withExistingEvents(2),
+					withRemoved(),
+				),
+			},
+			expectedErr: ErrSequenceNotMatched,
+			expectedConflicts: []*SequenceConflict{
+				{Aggregate: TextSubjects{"user", "id"}, Expected: ExpectExact(2), Actual: 1},
+			},
+		},
+		{
+			name: "unique constraint",
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withUniqueConstraints(AddUniqueConstraint("username", "username")),
+					withAdded("first name", "last name", "username"),
+				),
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "unique constraint violated",
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withUniqueConstraints(AddUniqueConstraint("username", "username")),
+					withAdded("first name", "last name", "username"),
+				),
+				newTestUser("2",
+					withUniqueConstraints(AddUniqueConstraint("username", "username")),
+					withAdded("first name", "last name", "username"),
+				),
+			},
+			expectedErr: ErrUniqueConstraintViolated,
+		},
+		{
+			name: "unique constraint released",
+			aggregates: []Aggregate{
+				newTestUser("id",
+					withUniqueConstraints(
+						AddUniqueConstraint("username", "username"),
+						RemoveUniqueConstraint("username", "username"),
+					),
+					withAdded("first name", "last name", "username"),
+				),
+				newTestUser("2",
+					withUniqueConstraints(AddUniqueConstraint("username", "username")),
+					withAdded("first name", "last name", "username"),
+				),
+			},
+			expectedErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		if err := store.Before(ctx, t); err != nil {
 			t.Error("unable to execute store.Before: ", err)
 		}
 		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.existing) > 0 {
+				pushDefaultCommands(ctx, t, store, tt.existing...)
+			}
 			err := store.Push(ctx, tt.aggregates...)
 			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("expected error was %v, got: %v", tt.expectedErr, err)
 			}
+			if tt.expectedConflicts != nil {
+				assertSequenceConflicts(t, err, tt.expectedConflicts)
+			}
 			if tt.expectedErr == nil {
 				assertAggregates(t, tt.aggregates)
 			}
@@ -499,10 +814,10 @@ func PushParallelOnDifferentAggregates(ctx context.Context, b *testing.B, store
 	}
 }
 
-func pushDefaultCommands(ctx context.Context, t testing.TB, store TestEventstore, aggregate Aggregate) {
+func pushDefaultCommands(ctx context.Context, t testing.TB, store TestEventstore, aggregates ...Aggregate) {
 	t.Helper()
 
-	err := store.Push(ctx, aggregate)
+	err := store.Push(ctx, aggregates...)
 	if err != nil {
 		t.Error(err)
 	}
@@ -582,6 +897,7 @@ func FilterComplianceTests(ctx context.Context, t *testing.T, store TestEventsto
 				FirstName: "first name",
 				LastName:  "last name",
 				Username:  "username",
+				metadata:  &Metadata{CorrelationID: "correlation", Creator: "creator"},
 			},
 			wantErr: false,
 		},
@@ -606,12 +922,36 @@ func FilterComplianceTests(ctx context.Context, t *testing.T, store TestEventsto
 			},
 			wantErr: false,
 		},
+		{
+			name: "correlation id",
+			args: args{
+				filter: &Filter{
+					Queries: []*FilterQuery{
+						{
+							Subjects:      []Subject{MultiToken},
+							CorrelationID: "correlation",
+						},
+					},
+				},
+			},
+			want: testUserReducer{
+				id:        "id",
+				sequence:  1,
+				isRemoved: false,
+				FirstName: "first name",
+				LastName:  "last name",
+				Username:  "username",
+				metadata:  &Metadata{CorrelationID: "correlation", Creator: "creator"},
+			},
+			wantErr: false,
+		},
 	}
 	if err := store.Before(ctx, t); err != nil {
 		t.Error("unable to execute store.Before: ", err)
 	}
 	err := store.Push(ctx,
 		newTestUser("id",
+			withMetadata(&Metadata{CorrelationID: "correlation", Creator: "creator"}),
 			withAdded("first name", "last name", "username"),
 			withRemoved(),
 		),
@@ -632,6 +972,484 @@ func FilterComplianceTests(ctx context.Context, t *testing.T, store TestEventsto
 			}
 		})
 	}
+	t.Run("await position", func(t *testing.T) {
+		user := newTestUser("await", withAdded("first name", "last name", "username"))
+		pushDefaultCommands(ctx, t, store, user)
+
+		added := user.Commands()[0].(*testUserAdded)
+		if added.position.IsZero() {
+			t.Fatal("position of command must be set")
+		}
+
+		got := testUserReducer{id: "await"}
+		err := store.Filter(ctx, &Filter{
+			Queries: []*FilterQuery{
+				{
+					Subjects: []Subject{TextSubject("user"), TextSubject("await"), MultiToken},
+				},
+			},
+			AwaitPosition: added.position,
+		}, &got)
+		if err != nil {
+			t.Fatalf("Filter() error = %v", err)
+		}
+		if got.sequence != 1 {
+			t.Errorf("pushed event not found, sequence: %d", got.sequence)
+		}
+	})
+	t.Run("iter break", func(t *testing.T) {
+		filter := &Filter{
+			Queries: []*FilterQuery{
+				{
+					Subjects: []Subject{TextSubject("user"), TextSubject("id"), MultiToken},
+				},
+			},
+		}
+		var count int
+		for event, err := range FilterIter(ctx, store, filter) {
+			if err != nil {
+				t.Fatalf("FilterIter() error = %v", err)
+			}
+			count++
+			if event.Sequence() != 1 {
+				t.Errorf("unexpected sequence want: 1, got: %d", event.Sequence())
+			}
+			break
+		}
+		if count != 1 {
+			t.Errorf("expected 1 event, got: %d", count)
+		}
+	})
+	if err := store.After(ctx, t); err != nil {
+		t.Error("unable to execute store.After: ", err)
+	}
+}
+
+func MetadataComplianceTests(ctx context.Context, t *testing.T, store TestEventstore) {
+	if err := store.Before(ctx, t); err != nil {
+		t.Error("unable to execute store.Before: ", err)
+	}
+
+	pushCtx := WithCausation(WithCorrelation(ctx, "correlation"), "cause")
+	err := store.Push(pushCtx,
+		newTestUser("id",
+			withMetadata(&Metadata{Creator: "creator"}),
+			withAdded("first name", "last name", "username"),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	filter := &Filter{
+		Queries: []*FilterQuery{
+			{
+				Subjects:      []Subject{MultiToken},
+				CorrelationID: "correlation",
+			},
+		},
+	}
+
+	var cause Event
+	t.Run("stamped from context", func(t *testing.T) {
+		for event, err := range FilterIter(ctx, store, filter) {
+			if err != nil {
+				t.Fatalf("FilterIter() error = %v", err)
+			}
+			want := &Metadata{CorrelationID: "correlation", CausationID: "cause", Creator: "creator"}
+			if !reflect.DeepEqual(event.Metadata(), want) {
+				t.Errorf("unexpected metadata want:\n%#v\ngot:\n%#v", want, event.Metadata())
+			}
+			if event.ID() == "" {
+				t.Error("event id must be set")
+			}
+			cause = &testCause{id: event.ID(), metadata: event.Metadata()}
+		}
+		if cause == nil {
+			t.Fatal("no event found")
+		}
+	})
+
+	t.Run("caused by event", func(t *testing.T) {
+		if cause == nil {
+			t.Skip("no cause found")
+		}
+		err := store.Push(WithCause(ctx, cause), newTestUser("2", withAdded("first name", "last name", "username")))
+		if err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+
+		var found bool
+		for event, err := range FilterIter(ctx, store, filter) {
+			if err != nil {
+				t.Fatalf("FilterIter() error = %v", err)
+			}
+			if event.Metadata().CausationID != cause.ID() {
+				continue
+			}
+			found = true
+			if event.Metadata().CorrelationID != "correlation" {
+				t.Errorf("correlation not inherited: %q", event.Metadata().CorrelationID)
+			}
+		}
+		if !found {
+			t.Error("no event caused by the event found")
+		}
+	})
+
+	t.Run("caused by routed event", func(t *testing.T) {
+		if cause == nil {
+			t.Skip("no cause found")
+		}
+		router := NewRouter().HandleContext([]Subject{MultiToken}, func(ctx context.Context, event Event) error {
+			if event.ID() != cause.ID() {
+				return nil
+			}
+			return store.Push(ctx, newTestUser("3", withAdded("first name", "last name", "username")))
+		})
+		if err := store.Filter(ctx, filter, router); err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+
+		var found int
+		for event, err := range FilterIter(ctx, store, filter) {
+			if err != nil {
+				t.Fatalf("FilterIter() error = %v", err)
+			}
+			if event.Metadata().CausationID == cause.ID() {
+				found++
+			}
+		}
+		// the event pushed in "caused by event" and the one pushed by the router
+		if found != 2 {
+			t.Errorf("expected 2 events caused by the event, got: %d", found)
+		}
+	})
+
+	if err := store.After(ctx, t); err != nil {
+		t.Error("unable to execute store.After: ", err)
+	}
+}
+
+// testCause keeps the fields of an event required by [WithCause]
+// because events are only valid during iteration
+type testCause struct {
+	Event
+	id       string
+	metadata *Metadata
+}
+
+// ID implements [Event]
+func (c *testCause) ID() string { return c.id }
+
+// Metadata implements [Event]
+func (c *testCause) Metadata() *Metadata { return c.metadata }
+
+func IdempotencyComplianceTests(ctx context.Context, t *testing.T, store TestEventstore) {
+	if err := store.Before(ctx, t); err != nil {
+		t.Error("unable to execute store.Before: ", err)
+	}
+
+	first := newTestUser("id",
+		withIdempotencyKey("key"),
+		withAdded("first name", "last name", "username"),
+	)
+	if err := store.Push(ctx, first); err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	t.Run("retry is skipped", func(t *testing.T) {
+		retry := newTestUser("id",
+			withPredefinedSequence(0),
+			withIdempotencyKey("key"),
+			withAdded("first name", "last name", "username"),
+		)
+		if err := store.Push(ctx, retry); err != nil {
+			t.Fatalf("retry must not fail: %v", err)
+		}
+		assertAggregates(t, []Aggregate{retry})
+		if added := retry.Commands()[0].(*testUserAdded); !added.deduplicated {
+			t.Error("command must be marked as deduplicated")
+		}
+	})
+
+	t.Run("different key is stored", func(t *testing.T) {
+		other := newTestUser("2",
+			withPredefinedSequence(0),
+			withIdempotencyKey("other"),
+			withAdded("first name", "last name", "username"),
+		)
+		if err := store.Push(ctx, other); err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+		assertAggregates(t, []Aggregate{other})
+		if added := other.Commands()[0].(*testUserAdded); added.deduplicated {
+			t.Error("command must not be marked as deduplicated")
+		}
+	})
+
+	t.Run("stored once", func(t *testing.T) {
+		filter := &Filter{
+			Queries: []*FilterQuery{
+				{
+					Subjects: []Subject{TextSubject("user"), TextSubject("id"), TextSubject("added")},
+				},
+			},
+		}
+		var count int
+		for _, err := range FilterIter(ctx, store, filter) {
+			if err != nil {
+				t.Fatalf("FilterIter() error = %v", err)
+			}
+			count++
+		}
+		if count != 1 {
+			t.Errorf("expected 1 event, got: %d", count)
+		}
+	})
+
+	if err := store.After(ctx, t); err != nil {
+		t.Error("unable to execute store.After: ", err)
+	}
+}
+
+func AppendConditionComplianceTests(ctx context.Context, t *testing.T, store TestEventstore) {
+	if err := store.Before(ctx, t); err != nil {
+		t.Error("unable to execute store.Before: ", err)
+	}
+
+	err := store.Push(ctx, newTestUser("id", withAdded("first name", "last name", "username")))
+	if err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	decision := &Filter{
+		Queries: []*FilterQuery{
+			{
+				Subjects: []Subject{TextSubject("user"), TextSubject("id"), MultiToken},
+			},
+		},
+	}
+	var position Position
+	for event, err := range FilterIter(ctx, store, decision) {
+		if err != nil {
+			t.Fatalf("FilterIter() error = %v", err)
+		}
+		position = event.Position()
+	}
+	if position.IsZero() {
+		t.Fatal("position of event must be set")
+	}
+
+	t.Run("no newer event", func(t *testing.T) {
+		err := store.Push(ctx,
+			newTestUser("2",
+				withAppendCondition(&AppendCondition{Filter: decision, After: position}),
+				withAdded("first name", "last name", "username"),
+			),
+		)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("events of other streams", func(t *testing.T) {
+		err := store.Push(ctx,
+			newTestUser("3",
+				withAppendCondition(&AppendCondition{Filter: decision, After: position}),
+				withAdded("first name", "last name", "username"),
+			),
+		)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("newer event", func(t *testing.T) {
+		err := store.Push(ctx, newTestUser("id", withUsername("changed")))
+		if err != nil {
+			t.Fatalf("unable to push events: %v", err)
+		}
+
+		err = store.Push(ctx,
+			newTestUser("4",
+				withAppendCondition(&AppendCondition{Filter: decision, After: position}),
+				withAdded("first name", "last name", "username"),
+			),
+		)
+		if !errors.Is(err, ErrAppendConditionFailed) {
+			t.Errorf("expected error was %v, got: %v", ErrAppendConditionFailed, err)
+		}
+	})
+
+	if err := store.After(ctx, t); err != nil {
+		t.Error("unable to execute store.After: ", err)
+	}
+}
+
+func CryptoShreddingComplianceTests(ctx context.Context, t *testing.T, store TestEventstore) {
+	forgetter, ok := store.(Forgetter)
+	if !ok {
+		t.Skip("store does not implement Forgetter")
+	}
+
+	if err := store.Before(ctx, t); err != nil {
+		t.Error("unable to execute store.Before: ", err)
+	}
+
+	err := store.Push(ctx,
+		newTestUser("id",
+			withDataSubject("id"),
+			withAdded("first name", "last name", "username"),
+		),
+		newTestUser("2",
+			withDataSubject("2"),
+			withAdded("first name", "last name", "username"),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unable to push events: %v", err)
+	}
+
+	userFilter := func(id string) *Filter {
+		return &Filter{
+			Queries: []*FilterQuery{
+				{
+					Subjects: []Subject{TextSubject("user"), TextSubject(id), MultiToken},
+				},
+			},
+		}
+	}
+
+	t.Run("readable", func(t *testing.T) {
+		reducer := &testUserReducer{id: "id"}
+		if err := store.Filter(ctx, userFilter("id"), reducer); err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		if reducer.FirstName != "first name" || reducer.LastName != "last name" || reducer.Username != "username" {
+			t.Errorf("unexpected payload: %+v", reducer)
+		}
+	})
+
+	if err = forgetter.ForgetSubject(ctx, "id"); err != nil {
+		t.Fatalf("unable to forget subject: %v", err)
+	}
+
+	t.Run("forgotten", func(t *testing.T) {
+		var count int
+		for event, err := range FilterIter(ctx, store, userFilter("id")) {
+			if err != nil {
+				t.Fatalf("FilterIter() error = %v", err)
+			}
+			count++
+			err = event.UnmarshalPayload(new(testUserReducer))
+			var forgotten *ForgottenError
+			if !errors.As(err, &forgotten) || forgotten.Subject != "id" {
+				t.Errorf("expected %v of subject \"id\", got: %v", ErrForgotten, err)
+			}
+		}
+		if count != 1 {
+			t.Errorf("event must be kept, got %d events", count)
+		}
+	})
+
+	t.Run("other subject readable", func(t *testing.T) {
+		reducer := &testUserReducer{id: "2"}
+		if err := store.Filter(ctx, userFilter("2"), reducer); err != nil {
+			t.Fatalf("unable to filter events: %v", err)
+		}
+		if reducer.FirstName != "first name" {
+			t.Errorf("unexpected payload: %+v", reducer)
+		}
+	})
+
+	if err := store.After(ctx, t); err != nil {
+		t.Error("unable to execute store.After: ", err)
+	}
+}
+
+func TenantComplianceTests(ctx context.Context, t *testing.T, store TestEventstore) {
+	if err := store.Before(ctx, t); err != nil {
+		t.Error("unable to execute store.Before: ", err)
+	}
+
+	for _, tenant := range []string{"a", "b"} {
+		// sequences and unique constraints are scoped to the tenant
+		err := store.Push(WithTenant(ctx, tenant),
+			newTestUser("id",
+				withPredefinedSequence(0),
+				withUniqueConstraints(AddUniqueConstraint("username", "username")),
+				withAdded("first name", "last name", "username"),
+			),
+		)
+		if err != nil {
+			t.Fatalf("unable to push events of tenant %q: %v", tenant, err)
+		}
+	}
+	// the tenant of the aggregate takes precedence over the one of the context
+	err := store.Push(WithTenant(ctx, "a"), newTestUser("id", withAggregateTenant("b"), withPredefinedSequence(1), withFirstName("changed")))
+	if err != nil {
+		t.Fatalf("unable to push events of aggregate tenant: %v", err)
+	}
+
+	filter := func(crossTenant bool) *Filter {
+		return &Filter{
+			Queries: []*FilterQuery{
+				{
+					Subjects: []Subject{TextSubject("user"), TextSubject("id"), MultiToken},
+				},
+			},
+			CrossTenant: crossTenant,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		crossTenant bool
+		want        int
+	}{
+		{
+			name: "default tenant",
+			ctx:  ctx,
+			want: 0,
+		},
+		{
+			name: "tenant a",
+			ctx:  WithTenant(ctx, "a"),
+			want: 1,
+		},
+		{
+			name: "tenant b",
+			ctx:  WithTenant(ctx, "b"),
+			want: 2,
+		},
+		{
+			name:        "cross tenant",
+			ctx:         WithTenant(ctx, "a"),
+			crossTenant: true,
+			want:        3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var count int
+			for event, err := range FilterIter(tt.ctx, store, filter(tt.crossTenant)) {
+				if err != nil {
+					t.Fatalf("FilterIter() error = %v", err)
+				}
+				count++
+				tenantEvent, ok := event.(TenantEvent)
+				if ok && !tt.crossTenant && tenantEvent.Tenant() != TenantFromContext(tt.ctx) {
+					t.Errorf("unexpected tenant of event want: %q, got: %q", TenantFromContext(tt.ctx), tenantEvent.Tenant())
+				}
+			}
+			if count != tt.want {
+				t.Errorf("unexpected event count want: %d, got: %d", tt.want, count)
+			}
+		})
+	}
+
 	if err := store.After(ctx, t); err != nil {
 		t.Error("unable to execute store.After: ", err)
 	}
@@ -796,6 +1614,19 @@ func FilterBenchTests(ctx context.Context, b *testing.B, store TestEventstore) {
 	}
 }
 
+func assertSequenceConflicts(t *testing.T, err error, want []*SequenceConflict) {
+	t.Helper()
+
+	var conflictErr *SequenceNotMatchedError
+	if !errors.As(err, &conflictErr) {
+		t.Errorf("expected %T, got: %v", conflictErr, err)
+		return
+	}
+	if !reflect.DeepEqual(conflictErr.Conflicts, want) {
+		t.Errorf("unexpected conflicts want:\n%v\ngot:\n%v", want, conflictErr.Conflicts)
+	}
+}
+
 type commandAsserter interface {
 	assert(t *testing.T) bool
 }
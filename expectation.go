@@ -0,0 +1,86 @@
+package eventstore
+
+import "strconv"
+
+// AggregateExpectation can be implemented by an [Aggregate]
+// to describe the expected state of the aggregate in the eventstore.
+// It takes precedence over [Aggregate.CurrentSequence].
+type AggregateExpectation interface {
+	Expectation() Expectation
+}
+
+// Expectation describes the expected state of an aggregate
+// before its commands are stored
+type Expectation struct {
+	mode     expectationMode
+	sequence uint32
+}
+
+type expectationMode uint8
+
+const (
+	expectAny expectationMode = iota
+	expectNoStream
+	expectStreamExists
+	expectExact
+)
+
+var (
+	// ExpectAny doesn't check the state of the aggregate
+	ExpectAny = Expectation{mode: expectAny}
+	// ExpectNoStream requires that no events of the aggregate exist
+	ExpectNoStream = Expectation{mode: expectNoStream}
+	// ExpectStreamExists requires at least one event of the aggregate
+	ExpectStreamExists = Expectation{mode: expectStreamExists}
+)
+
+// ExpectExact requires the current sequence of the aggregate to be the given sequence
+func ExpectExact(sequence uint32) Expectation {
+	return Expectation{mode: expectExact, sequence: sequence}
+}
+
+// ExpectationOf returns the expectation of the aggregate.
+// If the aggregate doesn't implement [AggregateExpectation]
+// the expectation is derived from [Aggregate.CurrentSequence]
+func ExpectationOf(aggregate Aggregate) Expectation {
+	if expecting, ok := aggregate.(AggregateExpectation); ok {
+		return expecting.Expectation()
+	}
+	if sequence := aggregate.CurrentSequence(); sequence != nil {
+		return ExpectExact(*sequence)
+	}
+	return ExpectAny
+}
+
+// Matches checks if the current sequence of an aggregate meets the expectation
+// the current sequence of an aggregate without events is 0
+func (e Expectation) Matches(currentSequence uint32) bool {
+	switch e.mode {
+	case expectNoStream:
+		return currentSequence == 0
+	case expectStreamExists:
+		return currentSequence > 0
+	case expectExact:
+		return currentSequence == e.sequence
+	default:
+		return true
+	}
+}
+
+// IsAny returns true if the state of the aggregate is not checked
+func (e Expectation) IsAny() bool {
+	return e.mode == expectAny
+}
+
+func (e Expectation) String() string {
+	switch e.mode {
+	case expectNoStream:
+		return "no stream"
+	case expectStreamExists:
+		return "stream exists"
+	case expectExact:
+		return strconv.FormatUint(uint64(e.sequence), 10)
+	default:
+		return "any"
+	}
+}
@@ -0,0 +1,28 @@
+package eventstore
+
+import "testing"
+
+func TestExpectation_Matches(t *testing.T) {
+	tests := []struct {
+		name            string
+		expectation     Expectation
+		currentSequence uint32
+		want            bool
+	}{
+		{name: "any new", expectation: ExpectAny, currentSequence: 0, want: true},
+		{name: "any existing", expectation: ExpectAny, currentSequence: 3, want: true},
+		{name: "no stream new", expectation: ExpectNoStream, currentSequence: 0, want: true},
+		{name: "no stream existing", expectation: ExpectNoStream, currentSequence: 3, want: false},
+		{name: "stream exists new", expectation: ExpectStreamExists, currentSequence: 0, want: false},
+		{name: "stream exists existing", expectation: ExpectStreamExists, currentSequence: 3, want: true},
+		{name: "exact matches", expectation: ExpectExact(3), currentSequence: 3, want: true},
+		{name: "exact differs", expectation: ExpectExact(3), currentSequence: 2, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.expectation.Matches(tt.currentSequence); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
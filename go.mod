@@ -1,10 +1,19 @@
 module github.com/adlerhurst/eventstore/v2
 
-go 1.21
+go 1.23
 
-require github.com/cockroachdb/cockroach-go/v2 v2.3.5
+require (
+	github.com/cockroachdb/cockroach-go/v2 v2.3.5
+	github.com/fxamacker/cbor/v2 v2.7.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.36.1
+)
 
-require github.com/kr/text v0.2.0 // indirect
+require (
+	github.com/kr/text v0.2.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+)
 
 require (
 	github.com/gofrs/flock v0.8.1 // indirect
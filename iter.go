@@ -0,0 +1,51 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// FilterIterator can be implemented by an [Eventstore]
+// to stream the events of a [Filter] natively
+type FilterIterator interface {
+	// FilterIter returns the events matching the filter
+	// the resources of the query are released as soon as the loop ends
+	// an event might be reused after the iteration continues, use [Clone] to keep it
+	FilterIter(ctx context.Context, filter *Filter) iter.Seq2[Event, error]
+}
+
+// FilterIter returns the events matching the filter as an iterator.
+// If the store implements [FilterIterator] the native implementation is used,
+// otherwise the events are yielded from [Eventstore.Filter].
+//
+// An error is yielded at most once and ends the iteration.
+func FilterIter(ctx context.Context, store Eventstore, filter *Filter) iter.Seq2[Event, error] {
+	if iterator, ok := store.(FilterIterator); ok {
+		return iterator.FilterIter(ctx, filter)
+	}
+
+	return func(yield func(Event, error) bool) {
+		err := store.Filter(ctx, filter, reduceFunc(func(events ...Event) error {
+			for _, event := range events {
+				if !yield(event, nil) {
+					return errStopIteration
+				}
+			}
+			return nil
+		}))
+		if err != nil && !errors.Is(err, errStopIteration) {
+			yield(nil, err)
+		}
+	}
+}
+
+// errStopIteration is used to stop [Eventstore.Filter] if the loop ends early
+var errStopIteration = errors.New("iteration stopped")
+
+type reduceFunc func(events ...Event) error
+
+// Reduce implements [Reducer]
+func (f reduceFunc) Reduce(events ...Event) error {
+	return f(events...)
+}
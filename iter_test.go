@@ -0,0 +1,81 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var _ Eventstore = (*testFilterStore)(nil)
+
+type testFilterStore struct {
+	events []Event
+	err    error
+	// reduceErr is the error returned by the reducer
+	reduceErr error
+}
+
+// Ready implements [Eventstore]
+func (*testFilterStore) Ready(context.Context) error { return nil }
+
+// Push implements [Eventstore]
+func (*testFilterStore) Push(context.Context, ...Aggregate) error { return nil }
+
+// Filter implements [Eventstore]
+func (s *testFilterStore) Filter(ctx context.Context, _ *Filter, reducer Reducer) error {
+	for _, event := range s.events {
+		if s.reduceErr = Reduce(ctx, reducer, event); s.reduceErr != nil {
+			return s.reduceErr
+		}
+	}
+	return s.err
+}
+
+func TestFilterIter(t *testing.T) {
+	store := &testFilterStore{
+		events: []Event{
+			&testEvent{action: TextSubjects{"user", "1", "added"}},
+			&testEvent{action: TextSubjects{"user", "1", "removed"}},
+			&testEvent{action: TextSubjects{"user", "2", "added"}},
+		},
+	}
+
+	var count int
+	for event, err := range FilterIter(context.Background(), store, &Filter{}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		count++
+		if event.Action()[2] == "removed" {
+			break
+		}
+	}
+
+	if count != 2 {
+		t.Errorf("expected 2 events, got: %d", count)
+	}
+	if !errors.Is(store.reduceErr, errStopIteration) {
+		t.Errorf("filter was not stopped: %v", store.reduceErr)
+	}
+}
+
+func TestFilterIter_error(t *testing.T) {
+	expectedErr := errors.New("filter failed")
+	store := &testFilterStore{
+		events: []Event{&testEvent{action: TextSubjects{"user", "1", "added"}}},
+		err:    expectedErr,
+	}
+
+	var errCount int
+	for _, err := range FilterIter(context.Background(), store, &Filter{}) {
+		if err != nil {
+			errCount++
+			if !errors.Is(err, expectedErr) {
+				t.Errorf("expected %v, got: %v", expectedErr, err)
+			}
+		}
+	}
+	if errCount != 1 {
+		t.Errorf("expected error to be yielded once, got: %d", errCount)
+	}
+}
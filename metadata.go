@@ -0,0 +1,76 @@
+package eventstore
+
+import (
+	"context"
+	"maps"
+)
+
+type metadataKey int
+
+const (
+	correlationKey metadataKey = iota
+	causationKey
+)
+
+// WithCorrelation returns a copy of ctx with the correlation id
+// which is stamped on every command pushed with the context
+func WithCorrelation(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey, id)
+}
+
+// WithCausation returns a copy of ctx with the id of the event
+// which caused the commands pushed with the context
+func WithCausation(ctx context.Context, eventID string) context.Context {
+	return context.WithValue(ctx, causationKey, eventID)
+}
+
+// WithCause returns a copy of ctx which records the event as cause
+// of the commands pushed with the context.
+// If ctx has no correlation id the correlation id of the event is inherited.
+//
+// It's meant to be used by reducers which push follow-up commands.
+// Handlers registered with [Router.HandleContext] receive a context
+// which already records the event as cause.
+func WithCause(ctx context.Context, event Event) context.Context {
+	if CorrelationFromContext(ctx) == "" && event.Metadata() != nil && event.Metadata().CorrelationID != "" {
+		ctx = WithCorrelation(ctx, event.Metadata().CorrelationID)
+	}
+	return WithCausation(ctx, event.ID())
+}
+
+// CorrelationFromContext returns the correlation id set by [WithCorrelation]
+func CorrelationFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey).(string)
+	return id
+}
+
+// CausationFromContext returns the event id set by [WithCausation]
+func CausationFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(causationKey).(string)
+	return id
+}
+
+// StampMetadata merges the correlation and causation of ctx into the metadata
+// the values of the metadata take precedence over the ones of ctx.
+// The metadata is copied before it's changed.
+// nil is returned if neither the metadata nor ctx contain any information
+func StampMetadata(ctx context.Context, metadata *Metadata) *Metadata {
+	correlationID, causationID := CorrelationFromContext(ctx), CausationFromContext(ctx)
+	if correlationID == "" && causationID == "" {
+		return metadata
+	}
+
+	stamped := new(Metadata)
+	if metadata != nil {
+		*stamped = *metadata
+		stamped.Headers = maps.Clone(metadata.Headers)
+	}
+	if stamped.CorrelationID == "" {
+		stamped.CorrelationID = correlationID
+	}
+	if stamped.CausationID == "" {
+		stamped.CausationID = causationID
+	}
+
+	return stamped
+}
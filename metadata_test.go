@@ -0,0 +1,73 @@
+package eventstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStampMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		metadata *Metadata
+		want     *Metadata
+	}{
+		{
+			name:     "empty",
+			ctx:      context.Background(),
+			metadata: nil,
+			want:     nil,
+		},
+		{
+			name:     "only command",
+			ctx:      context.Background(),
+			metadata: &Metadata{Creator: "creator"},
+			want:     &Metadata{Creator: "creator"},
+		},
+		{
+			name:     "only context",
+			ctx:      WithCausation(WithCorrelation(context.Background(), "correlation"), "cause"),
+			metadata: nil,
+			want:     &Metadata{CorrelationID: "correlation", CausationID: "cause"},
+		},
+		{
+			name:     "command takes precedence",
+			ctx:      WithCausation(WithCorrelation(context.Background(), "correlation"), "cause"),
+			metadata: &Metadata{CorrelationID: "command", Headers: map[string]string{"key": "value"}},
+			want:     &Metadata{CorrelationID: "command", CausationID: "cause", Headers: map[string]string{"key": "value"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StampMetadata(tt.ctx, tt.metadata); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StampMetadata() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStampMetadata_copy(t *testing.T) {
+	metadata := &Metadata{Creator: "creator"}
+	StampMetadata(WithCorrelation(context.Background(), "correlation"), metadata)
+	if metadata.CorrelationID != "" {
+		t.Error("metadata of the command must not be changed")
+	}
+}
+
+func TestWithCause(t *testing.T) {
+	cause := &testCause{id: "event", metadata: &Metadata{CorrelationID: "correlation"}}
+
+	ctx := WithCause(context.Background(), cause)
+	if got := CausationFromContext(ctx); got != "event" {
+		t.Errorf("unexpected causation: %q", got)
+	}
+	if got := CorrelationFromContext(ctx); got != "correlation" {
+		t.Errorf("correlation not inherited: %q", got)
+	}
+
+	ctx = WithCause(WithCorrelation(context.Background(), "request"), cause)
+	if got := CorrelationFromContext(ctx); got != "request" {
+		t.Errorf("correlation of context must be kept: %q", got)
+	}
+}
@@ -0,0 +1,330 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrPIIPermissionRequired is returned by [Redactor] if a payload can't be redacted,
+// e.g. if it's decoded into a map or read using [RawPayload]
+var ErrPIIPermissionRequired = errors.New("payload can only be read with pii permission")
+
+type piiKey struct{}
+
+// WithPIIPermission returns a copy of ctx which allows
+// to read the fields of payloads tagged as personal data
+func WithPIIPermission(ctx context.Context) context.Context {
+	return context.WithValue(ctx, piiKey{}, true)
+}
+
+// HasPIIPermission checks if ctx was created by [WithPIIPermission]
+func HasPIIPermission(ctx context.Context) bool {
+	allowed, _ := ctx.Value(piiKey{}).(bool)
+	return allowed
+}
+
+var (
+	_ Eventstore     = (*Redactor)(nil)
+	_ FilterIterator = (*Redactor)(nil)
+)
+
+// Redactor wraps an [Eventstore] and redacts the personal data of the payloads
+// of the filtered events if the context lacks [WithPIIPermission].
+//
+// Fields of the payload are marked as personal data using the struct tag
+//   - `eventstore:"pii"` sets the field to its zero value
+//   - `eventstore:"pii,mask"` keeps the first character of strings
+//     and replaces the others with '*', other types are set to their zero value
+//
+// Tagged fields are redacted as a whole regardless of their type, e.g. maps, [any] or [json.RawMessage].
+// Nested structs, pointers, slices, arrays and maps are redacted recursively.
+// Payloads can only be decoded into structs, or slices, arrays and maps of structs,
+// because untyped targets like maps, [any] or [json.RawMessage] don't have tagged fields.
+// The same applies to untagged fields of these types, e.g. `Data map[string]any`.
+// Decoding into other targets and reading raw payloads using [RawPayload]
+// returns [ErrPIIPermissionRequired].
+// [TenantEvent], [SignedEvent] and [RedactedEvent] are forwarded to the filtered events.
+type Redactor struct {
+	store Eventstore
+}
+
+// NewRedactor redacts the events filtered from store
+func NewRedactor(store Eventstore) *Redactor {
+	return &Redactor{store: store}
+}
+
+// Ready implements [Eventstore]
+func (r *Redactor) Ready(ctx context.Context) error {
+	return r.store.Ready(ctx)
+}
+
+// Push implements [Eventstore]
+func (r *Redactor) Push(ctx context.Context, aggregates ...Aggregate) error {
+	return r.store.Push(ctx, aggregates...)
+}
+
+// Filter implements [Eventstore]
+func (r *Redactor) Filter(ctx context.Context, filter *Filter, reducer Reducer) error {
+	if HasPIIPermission(ctx) {
+		return r.store.Filter(ctx, filter, reducer)
+	}
+	return r.store.Filter(ctx, filter, withBatching(reducer, &redactingReducer{reducer: reducer}))
+}
+
+var _ ContextReducer = (*redactingReducer)(nil)
+
+// redactingReducer passes redacted events to reducer
+type redactingReducer struct {
+	reducer Reducer
+}
+
+// Reduce implements [Reducer]
+func (r *redactingReducer) Reduce(events ...Event) error {
+	return r.reducer.Reduce(redact(events)...)
+}
+
+// ReduceContext implements [ContextReducer]
+func (r *redactingReducer) ReduceContext(ctx context.Context, events ...Event) error {
+	return Reduce(ctx, r.reducer, redact(events)...)
+}
+
+func redact(events []Event) []Event {
+	redacted := make([]Event, len(events))
+	for i, event := range events {
+		redacted[i] = &redactedEvent{Event: event}
+	}
+	return redacted
+}
+
+// FilterIter implements [FilterIterator]
+func (r *Redactor) FilterIter(ctx context.Context, filter *Filter) iter.Seq2[Event, error] {
+	events := FilterIter(ctx, r.store, filter)
+	if HasPIIPermission(ctx) {
+		return events
+	}
+	return func(yield func(Event, error) bool) {
+		for event, err := range events {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&redactedEvent{Event: event}, nil) {
+				return
+			}
+		}
+	}
+}
+
+var (
+	_ CloneableEvent  = (*redactedEvent)(nil)
+	_ RawPayloadEvent = (*redactedEvent)(nil)
+	_ TenantEvent     = (*redactedEvent)(nil)
+	_ SignedEvent     = (*redactedEvent)(nil)
+	_ RedactedEvent   = (*redactedEvent)(nil)
+)
+
+type redactedEvent struct {
+	Event
+}
+
+// Clone implements [CloneableEvent]
+func (e *redactedEvent) Clone() Event {
+	return &redactedEvent{Event: Clone(e.Event)}
+}
+
+// UnmarshalPayload implements [Event]
+// the personal data of the object is redacted
+func (e *redactedEvent) UnmarshalPayload(object any) error {
+	value := reflect.ValueOf(object)
+	if value.Kind() != reflect.Pointer || !isRedactable(value.Type().Elem()) {
+		return ErrPIIPermissionRequired
+	}
+	if err := e.Event.UnmarshalPayload(object); err != nil {
+		return err
+	}
+	RedactPII(object)
+	return nil
+}
+
+// RawPayload implements [RawPayloadEvent]
+// raw payloads can't be redacted
+func (e *redactedEvent) RawPayload() ([]byte, error) {
+	return nil, ErrPIIPermissionRequired
+}
+
+// PayloadCodec implements [RawPayloadEvent]
+func (e *redactedEvent) PayloadCodec() string {
+	if raw, ok := e.Event.(RawPayloadEvent); ok {
+		return raw.PayloadCodec()
+	}
+	return JSONCodec.Name()
+}
+
+// Tenant implements [TenantEvent]
+func (e *redactedEvent) Tenant() string {
+	if tenant, ok := e.Event.(TenantEvent); ok {
+		return tenant.Tenant()
+	}
+	return ""
+}
+
+// KeyID implements [SignedEvent]
+func (e *redactedEvent) KeyID() string {
+	if signed, ok := e.Event.(SignedEvent); ok {
+		return signed.KeyID()
+	}
+	return ""
+}
+
+// VerifySignature implements [SignedEvent]
+// the signature is verified against the stored payload
+func (e *redactedEvent) VerifySignature(verifier Verifier) error {
+	if signed, ok := e.Event.(SignedEvent); ok {
+		return signed.VerifySignature(verifier)
+	}
+	return ErrUnsigned
+}
+
+// RedactedAt implements [RedactedEvent]
+func (e *redactedEvent) RedactedAt() time.Time {
+	if redacted, ok := e.Event.(RedactedEvent); ok {
+		return redacted.RedactedAt()
+	}
+	return time.Time{}
+}
+
+// isRedactable checks if the personal data of values of typ can be identified by tags,
+// i.e. typ is based on a struct and its untagged fields don't contain untyped content
+func isRedactable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return isRedactable(typ.Elem())
+	case reflect.Struct:
+		return !hasUntypedFields(typ, make(map[reflect.Type]bool))
+	default:
+		return false
+	}
+}
+
+// hasUntypedFields checks if a field of typ which is not tagged as personal data
+// contains values without tags, e.g. [any], maps of [any], [json.RawMessage] or []byte
+func hasUntypedFields(typ reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[typ] {
+		return false
+	}
+	visited[typ] = true
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() || isPIIField(field) {
+			continue
+		}
+		if isUntyped(field.Type, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUntyped checks if values of typ can contain content without tags
+func isUntyped(typ reflect.Type, visited map[reflect.Type]bool) bool {
+	switch typ.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Slice:
+		// raw content like []byte or [json.RawMessage]
+		return typ.Elem().Kind() == reflect.Uint8 || isUntyped(typ.Elem(), visited)
+	case reflect.Pointer, reflect.Array, reflect.Map:
+		return isUntyped(typ.Elem(), visited)
+	case reflect.Struct:
+		return hasUntypedFields(typ, visited)
+	default:
+		return false
+	}
+}
+
+const (
+	piiTag     = "eventstore"
+	piiTagName = "pii"
+	piiMask    = "mask"
+)
+
+// RedactPII redacts the fields of object tagged as personal data in place,
+// see [Redactor] for the supported tags.
+// object must be a pointer, other values are ignored.
+// Untagged fields with untyped content like `Data map[string]any` are kept as is.
+func RedactPII(object any) {
+	value := reflect.ValueOf(object)
+	if value.Kind() != reflect.Pointer {
+		return
+	}
+	redactValue(value.Elem())
+}
+
+func redactValue(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !value.IsNil() {
+			redactValue(value.Elem())
+		}
+	case reflect.Struct:
+		redactStruct(value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			redactValue(value.Index(i))
+		}
+	case reflect.Map:
+		redactMap(value)
+	}
+}
+
+func redactStruct(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		structField := value.Type().Field(i)
+		if !isPIIField(structField) {
+			redactValue(field)
+			continue
+		}
+		if _, option, _ := strings.Cut(structField.Tag.Get(piiTag), ","); option == piiMask && field.Kind() == reflect.String {
+			field.SetString(mask(field.String()))
+			continue
+		}
+		field.SetZero()
+	}
+}
+
+// isPIIField checks if the field is tagged as personal data
+func isPIIField(field reflect.StructField) bool {
+	name, _, _ := strings.Cut(field.Tag.Get(piiTag), ",")
+	return name == piiTagName
+}
+
+// redactMap redacts the values of the map
+// map values are not addressable, therefore they are copied
+func redactMap(value reflect.Value) {
+	iter := value.MapRange()
+	for iter.Next() {
+		elem := reflect.New(iter.Value().Type()).Elem()
+		elem.Set(iter.Value())
+		redactValue(elem)
+		value.SetMapIndex(iter.Key(), elem)
+	}
+}
+
+// mask keeps the first character of text and replaces the others with '*'
+func mask(text string) string {
+	if text == "" {
+		return text
+	}
+	_, size := utf8.DecodeRuneInString(text)
+	return text[:size] + strings.Repeat("*", utf8.RuneCountInString(text[size:]))
+}
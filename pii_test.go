@@ -0,0 +1,367 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	Street string `json:"street" eventstore:"pii"`
+	City   string `json:"city"`
+}
+
+type testPIIPayload struct {
+	Username  string            `json:"username"`
+	Email     string            `json:"email" eventstore:"pii,mask"`
+	Birthday  string            `json:"birthday" eventstore:"pii"`
+	Age       int               `json:"age" eventstore:"pii,mask"`
+	Address   *testAddress      `json:"address"`
+	Previous  []testAddress     `json:"previous"`
+	Addresses map[string]string `json:"addresses" eventstore:"pii"`
+	Other     string            `json:"other" eventstore:"other"`
+}
+
+func TestRedactPII(t *testing.T) {
+	tests := []struct {
+		name   string
+		object any
+		want   any
+	}{
+		{
+			name: "struct",
+			object: &testPIIPayload{
+				Username:  "gigi",
+				Email:     "gigi@example.com",
+				Birthday:  "1970-01-01",
+				Age:       42,
+				Address:   &testAddress{Street: "main street", City: "zurich"},
+				Previous:  []testAddress{{Street: "side street", City: "bern"}},
+				Addresses: map[string]string{"home": "main street"},
+				Other:     "other",
+			},
+			want: &testPIIPayload{
+				Username: "gigi",
+				Email:    "g***************",
+				Address:  &testAddress{City: "zurich"},
+				Previous: []testAddress{{City: "bern"}},
+				Other:    "other",
+			},
+		},
+		{
+			name:   "nil pointer",
+			object: &testPIIPayload{},
+			want:   &testPIIPayload{},
+		},
+		{
+			name:   "map of structs",
+			object: &map[string]testAddress{"home": {Street: "main street", City: "zurich"}},
+			want:   &map[string]testAddress{"home": {City: "zurich"}},
+		},
+		{
+			name: "tagged untyped fields",
+			object: &testTaggedUntypedPayload{
+				Username: "gigi",
+				Data:     map[string]any{"email": "gigi@example.com"},
+				Raw:      json.RawMessage(`{"email":"gigi@example.com"}`),
+				Value:    "gigi@example.com",
+			},
+			want: &testTaggedUntypedPayload{Username: "gigi"},
+		},
+		{
+			name:   "multi byte mask",
+			object: &testPIIPayload{Email: "ümlaut"},
+			want:   &testPIIPayload{Email: "ü*****"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RedactPII(tt.object)
+			if !reflect.DeepEqual(tt.object, tt.want) {
+				t.Errorf("unexpected redaction want:\n%#v\ngot:\n%#v", tt.want, tt.object)
+			}
+		})
+	}
+}
+
+type testPayloadEvent struct {
+	*testEvent
+	payload []byte
+}
+
+// UnmarshalPayload implements [Event]
+func (e *testPayloadEvent) UnmarshalPayload(object any) error {
+	return json.Unmarshal(e.payload, object)
+}
+
+func TestRedactor_Filter(t *testing.T) {
+	store := NewRedactor(&testFilterStore{
+		events: []Event{
+			&testPayloadEvent{
+				testEvent: &testEvent{action: TextSubjects{"user", "1", "added"}},
+				payload:   []byte(`{"username":"gigi","email":"gigi@example.com","birthday":"1970-01-01"}`),
+			},
+		},
+	})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want *testPIIPayload
+	}{
+		{
+			name: "without permission",
+			ctx:  context.Background(),
+			want: &testPIIPayload{Username: "gigi", Email: "g***************"},
+		},
+		{
+			name: "with permission",
+			ctx:  WithPIIPermission(context.Background()),
+			want: &testPIIPayload{Username: "gigi", Email: "gigi@example.com", Birthday: "1970-01-01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *testPIIPayload
+			err := store.Filter(tt.ctx, &Filter{}, reduceFunc(func(events ...Event) error {
+				got = new(testPIIPayload)
+				return events[0].UnmarshalPayload(got)
+			}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected payload of Filter want:\n%#v\ngot:\n%#v", tt.want, got)
+			}
+
+			for event, err := range FilterIter(tt.ctx, store, &Filter{}) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got = new(testPIIPayload)
+				if err = event.UnmarshalPayload(got); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected payload of FilterIter want:\n%#v\ngot:\n%#v", tt.want, got)
+			}
+		})
+	}
+}
+
+type testUntypedPayload struct {
+	Username string         `json:"username"`
+	Data     map[string]any `json:"data"`
+}
+
+type testRawPayload struct {
+	Username string          `json:"username"`
+	Data     json.RawMessage `json:"data"`
+}
+
+type testTaggedUntypedPayload struct {
+	Username string          `json:"username"`
+	Data     map[string]any  `json:"data" eventstore:"pii"`
+	Raw      json.RawMessage `json:"raw" eventstore:"pii,mask"`
+	Value    any             `json:"value" eventstore:"pii"`
+}
+
+type testRecursivePayload struct {
+	Username string                  `json:"username"`
+	Children []*testRecursivePayload `json:"children"`
+}
+
+func TestRedactor_Filter_untyped(t *testing.T) {
+	store := NewRedactor(&testFilterStore{
+		events: []Event{
+			&testPayloadEvent{
+				testEvent: &testEvent{action: TextSubjects{"user", "1", "added"}},
+				payload:   []byte(`{"username":"gigi","email":"gigi@example.com","birthday":"1970-01-01"}`),
+			},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		read    func(Event) error
+		wantErr error
+	}{
+		{
+			name: "map without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(&map[string]any{})
+			},
+			wantErr: ErrPIIPermissionRequired,
+		},
+		{
+			name: "any without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				var payload any
+				return event.UnmarshalPayload(&payload)
+			},
+			wantErr: ErrPIIPermissionRequired,
+		},
+		{
+			name: "raw message without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				var payload json.RawMessage
+				return event.UnmarshalPayload(&payload)
+			},
+			wantErr: ErrPIIPermissionRequired,
+		},
+		{
+			name: "raw payload without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				_, _, err := RawPayload(event)
+				return err
+			},
+			wantErr: ErrPIIPermissionRequired,
+		},
+		{
+			name: "struct without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(new(testPIIPayload))
+			},
+		},
+		{
+			name: "untagged map field without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(new(testUntypedPayload))
+			},
+			wantErr: ErrPIIPermissionRequired,
+		},
+		{
+			name: "untagged raw message field without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(&[]testRawPayload{})
+			},
+			wantErr: ErrPIIPermissionRequired,
+		},
+		{
+			name: "tagged untyped fields without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(new(testTaggedUntypedPayload))
+			},
+		},
+		{
+			name: "recursive struct without permission",
+			ctx:  context.Background(),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(new(testRecursivePayload))
+			},
+		},
+		{
+			name: "untagged map field with permission",
+			ctx:  WithPIIPermission(context.Background()),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(new(testUntypedPayload))
+			},
+		},
+		{
+			name: "map with permission",
+			ctx:  WithPIIPermission(context.Background()),
+			read: func(event Event) error {
+				return event.UnmarshalPayload(&map[string]any{})
+			},
+		},
+		{
+			name: "raw payload with permission",
+			ctx:  WithPIIPermission(context.Background()),
+			read: func(event Event) error {
+				_, _, err := RawPayload(event)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for event, err := range FilterIter(tt.ctx, store, &Filter{}) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if err = tt.read(event); !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got: %v", tt.wantErr, err)
+				}
+			}
+		})
+	}
+}
+
+type testStoredEvent struct {
+	*testEvent
+	tenant     string
+	keyID      string
+	redactedAt time.Time
+}
+
+// Tenant implements [TenantEvent]
+func (e *testStoredEvent) Tenant() string { return e.tenant }
+
+// KeyID implements [SignedEvent]
+func (e *testStoredEvent) KeyID() string { return e.keyID }
+
+// VerifySignature implements [SignedEvent]
+func (*testStoredEvent) VerifySignature(Verifier) error { return nil }
+
+// RedactedAt implements [RedactedEvent]
+func (e *testStoredEvent) RedactedAt() time.Time { return e.redactedAt }
+
+func Test_redactedEvent_optionalInterfaces(t *testing.T) {
+	redactedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		event          Event
+		wantTenant     string
+		wantKeyID      string
+		wantVerifyErr  error
+		wantRedactedAt time.Time
+	}{
+		{
+			name: "forwarded",
+			event: &testStoredEvent{
+				testEvent:  &testEvent{action: TextSubjects{"user", "1", "added"}},
+				tenant:     "tenant",
+				keyID:      "key",
+				redactedAt: redactedAt,
+			},
+			wantTenant:     "tenant",
+			wantKeyID:      "key",
+			wantRedactedAt: redactedAt,
+		},
+		{
+			name:          "not implemented",
+			event:         &testEvent{action: TextSubjects{"user", "1", "added"}},
+			wantVerifyErr: ErrUnsigned,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event Event = &redactedEvent{Event: tt.event}
+			if got := event.(TenantEvent).Tenant(); got != tt.wantTenant {
+				t.Errorf("unexpected tenant want: %q, got: %q", tt.wantTenant, got)
+			}
+			if got := event.(SignedEvent).KeyID(); got != tt.wantKeyID {
+				t.Errorf("unexpected key id want: %q, got: %q", tt.wantKeyID, got)
+			}
+			if err := event.(SignedEvent).VerifySignature(nil); !errors.Is(err, tt.wantVerifyErr) {
+				t.Errorf("unexpected verification error want: %v, got: %v", tt.wantVerifyErr, err)
+			}
+			if got := event.(RedactedEvent).RedactedAt(); !got.Equal(tt.wantRedactedAt) {
+				t.Errorf("unexpected redaction time want: %v, got: %v", tt.wantRedactedAt, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package eventstore
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Position is the global position of an event in the eventstore
+// represented as exact decimal, e.g. "1760890000000000000.0000000001".
+// Positions must not be converted to floats because the fraction
+// which orders events of the same time would be lost.
+// The zero value is an empty position.
+type Position string
+
+// IsZero checks if the position is empty
+func (p Position) IsZero() bool {
+	return p == ""
+}
+
+// Compare returns -1 if p is before other, 0 if they are equal and +1 if p is after other.
+// Empty positions are before all other positions.
+func (p Position) Compare(other Position) int {
+	switch {
+	case p == other:
+		return 0
+	case p.IsZero():
+		return -1
+	case other.IsZero():
+		return 1
+	}
+	left, leftOK := new(big.Rat).SetString(string(p))
+	right, rightOK := new(big.Rat).SetString(string(other))
+	if !leftOK || !rightOK {
+		return strings.Compare(string(p), string(other))
+	}
+	return left.Cmp(right)
+}
+
+// String implements [fmt.Stringer]
+func (p Position) String() string {
+	return string(p)
+}
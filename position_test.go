@@ -0,0 +1,49 @@
+package eventstore
+
+import "testing"
+
+func TestPosition_Compare(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right Position
+		want        int
+	}{
+		{
+			name: "equal",
+			left: "1760890000000000000.0000000001", right: "1760890000000000000.0000000001",
+			want: 0,
+		},
+		{
+			name: "equal with different scale",
+			left: "1760890000000000000.1", right: "1760890000000000000.1000000000",
+			want: 0,
+		},
+		{
+			name: "logical counter",
+			left: "1760890000000000000.0000000001", right: "1760890000000000000.0000000002",
+			want: -1,
+		},
+		{
+			name: "below float precision",
+			left: "1760890000000000100.0000000000", right: "1760890000000000000.0000000001",
+			want: 1,
+		},
+		{
+			name: "empty",
+			left: "", right: "1",
+			want: -1,
+		},
+		{
+			name: "both empty",
+			left: "", right: "",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.left.Compare(tt.right); got != tt.want {
+				t.Errorf("Compare() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
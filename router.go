@@ -0,0 +1,160 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	_ Reducer        = (*Router)(nil)
+	_ ContextReducer = (*Router)(nil)
+)
+
+// HandlerFunc handles a single event routed by the [Router]
+type HandlerFunc func(event Event) error
+
+// ContextHandlerFunc handles a single event routed by the [Router]
+// ctx records the event as cause, see [WithCause],
+// therefore commands pushed with ctx are stamped with the event as their cause.
+type ContextHandlerFunc func(ctx context.Context, event Event) error
+
+// UnhandledPolicy is called by the [Router] for each event
+// which did not match any registered handler
+type UnhandledPolicy func(event Event) error
+
+var (
+	ErrUnhandledEvent = errors.New("no handler registered for event")
+
+	// IgnoreUnhandled skips events without a handler
+	IgnoreUnhandled UnhandledPolicy = func(Event) error { return nil }
+	// FailOnUnhandled returns [ErrUnhandledEvent] for events without a handler
+	FailOnUnhandled UnhandledPolicy = func(event Event) error {
+		return fmt.Errorf("%w: %s (revision %d)", ErrUnhandledEvent, event.Action().Join("."), event.Revision())
+	}
+)
+
+// Router is a [Reducer] which dispatches events to the handlers
+// registered for the subjects matching the action of the event.
+// Handlers are called in the order they were registered.
+type Router struct {
+	routes    []*route
+	unhandled UnhandledPolicy
+}
+
+type route struct {
+	subjects []Subject
+	revision *uint16
+	handler  ContextHandlerFunc
+}
+
+type routerOpt func(*Router)
+
+// WithUnhandledPolicy sets the policy for events without a matching handler
+// the default policy is [IgnoreUnhandled]
+func WithUnhandledPolicy(policy UnhandledPolicy) routerOpt {
+	return func(router *Router) {
+		router.unhandled = policy
+	}
+}
+
+func NewRouter(opts ...routerOpt) *Router {
+	router := &Router{
+		unhandled: IgnoreUnhandled,
+	}
+
+	for _, opt := range opts {
+		opt(router)
+	}
+
+	return router
+}
+
+type routeOpt func(*route)
+
+// WithRevision restricts the handler to events of the given revision
+func WithRevision(revision uint16) routeOpt {
+	return func(r *route) {
+		r.revision = &revision
+	}
+}
+
+// Handle registers the handler for all events whose action matches the subjects
+// [SingleToken] matches exactly one token, [MultiToken] matches all remaining tokens
+// and must therefore be the last subject
+func (router *Router) Handle(subjects []Subject, handler HandlerFunc, opts ...routeOpt) *Router {
+	return router.HandleContext(subjects, func(_ context.Context, event Event) error {
+		return handler(event)
+	}, opts...)
+}
+
+// HandleContext registers the handler like [Router.Handle].
+// The handler receives the context of the filter with the event recorded as cause
+// if the router is passed to [Eventstore.Filter], otherwise [context.Background].
+func (router *Router) HandleContext(subjects []Subject, handler ContextHandlerFunc, opts ...routeOpt) *Router {
+	r := &route{
+		subjects: subjects,
+		handler:  handler,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	router.routes = append(router.routes, r)
+	return router
+}
+
+// Reduce implements [Reducer]
+func (router *Router) Reduce(events ...Event) error {
+	return router.ReduceContext(context.Background(), events...)
+}
+
+// ReduceContext implements [ContextReducer]
+// the handlers receive ctx with the event recorded as cause
+func (router *Router) ReduceContext(ctx context.Context, events ...Event) error {
+	for _, event := range events {
+		var handled bool
+		for _, r := range router.routes {
+			if !r.matches(event) {
+				continue
+			}
+			handled = true
+			if err := r.handler(WithCause(ctx, event), event); err != nil {
+				return err
+			}
+		}
+		if handled {
+			continue
+		}
+		if err := router.unhandled(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *route) matches(event Event) bool {
+	if r.revision != nil && *r.revision != event.Revision() {
+		return false
+	}
+	return matchSubjects(event.Action(), r.subjects)
+}
+
+// matchSubjects checks if the action matches the subjects
+// the semantics are the same as the ones of [FilterQuery.Subjects]
+func matchSubjects(action TextSubjects, subjects []Subject) bool {
+	for i, subject := range subjects {
+		if subject == MultiToken {
+			// multi token requires at least one remaining token
+			return len(action) > i
+		}
+		if i >= len(action) {
+			return false
+		}
+		if textSubject, ok := subject.(TextSubject); ok && action[i] != textSubject {
+			return false
+		}
+	}
+	return len(action) == len(subjects)
+}
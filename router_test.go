@@ -0,0 +1,242 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var _ Event = (*testEvent)(nil)
+
+type testEvent struct {
+	action   TextSubjects
+	revision uint16
+}
+
+// ID implements [Event]
+func (*testEvent) ID() string { return "" }
+
+// Action implements [Event]
+func (e *testEvent) Action() TextSubjects { return e.action }
+
+// Revision implements [Event]
+func (e *testEvent) Revision() uint16 { return e.revision }
+
+// Aggregate implements [Event]
+func (e *testEvent) Aggregate() TextSubjects { return e.action[:len(e.action)-1] }
+
+// Sequence implements [Event]
+func (*testEvent) Sequence() uint32 { return 0 }
+
+// Position implements [Event]
+func (*testEvent) Position() Position { return "" }
+
+// CreationDate implements [Event]
+func (*testEvent) CreationDate() time.Time { return time.Time{} }
+
+// Metadata implements [Event]
+func (*testEvent) Metadata() *Metadata { return nil }
+
+// UnmarshalPayload implements [Event]
+func (*testEvent) UnmarshalPayload(any) error { return nil }
+
+func Test_matchSubjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   TextSubjects
+		subjects []Subject
+		want     bool
+	}{
+		{
+			name:     "equal",
+			action:   TextSubjects{"user", "1", "added"},
+			subjects: []Subject{TextSubject("user"), TextSubject("1"), TextSubject("added")},
+			want:     true,
+		},
+		{
+			name:     "different text",
+			action:   TextSubjects{"user", "1", "added"},
+			subjects: []Subject{TextSubject("user"), TextSubject("1"), TextSubject("removed")},
+			want:     false,
+		},
+		{
+			name:     "single token",
+			action:   TextSubjects{"user", "1", "added"},
+			subjects: []Subject{TextSubject("user"), SingleToken, TextSubject("added")},
+			want:     true,
+		},
+		{
+			name:     "single token too short",
+			action:   TextSubjects{"user", "1", "added"},
+			subjects: []Subject{TextSubject("user"), SingleToken},
+			want:     false,
+		},
+		{
+			name:     "single token too long",
+			action:   TextSubjects{"user", "1"},
+			subjects: []Subject{TextSubject("user"), SingleToken, SingleToken},
+			want:     false,
+		},
+		{
+			name:     "multi token",
+			action:   TextSubjects{"user", "1", "firstName", "set"},
+			subjects: []Subject{TextSubject("user"), MultiToken},
+			want:     true,
+		},
+		{
+			name:     "multi token requires token",
+			action:   TextSubjects{"user"},
+			subjects: []Subject{TextSubject("user"), MultiToken},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchSubjects(tt.action, tt.subjects); got != tt.want {
+				t.Errorf("matchSubjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_Reduce(t *testing.T) {
+	var called []string
+	handler := func(name string) HandlerFunc {
+		return func(Event) error {
+			called = append(called, name)
+			return nil
+		}
+	}
+
+	router := NewRouter().
+		Handle([]Subject{TextSubject("user"), SingleToken, TextSubject("added")}, handler("added")).
+		Handle([]Subject{TextSubject("user"), MultiToken}, handler("user")).
+		Handle([]Subject{TextSubject("user"), SingleToken, TextSubject("removed")}, handler("removed v2"), WithRevision(2))
+
+	err := router.Reduce(
+		&testEvent{action: TextSubjects{"user", "1", "added"}, revision: 1},
+		&testEvent{action: TextSubjects{"user", "1", "removed"}, revision: 1},
+		&testEvent{action: TextSubjects{"user", "1", "removed"}, revision: 2},
+		&testEvent{action: TextSubjects{"org", "1", "added"}, revision: 1},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"added", "user", "user", "user", "removed v2"}
+	if !reflect.DeepEqual(called, want) {
+		t.Errorf("unexpected handlers called want:\n%v\ngot:\n%v", want, called)
+	}
+}
+
+func TestRouter_Reduce_unhandled(t *testing.T) {
+	router := NewRouter(WithUnhandledPolicy(FailOnUnhandled)).
+		Handle([]Subject{TextSubject("user"), MultiToken}, func(Event) error { return nil })
+
+	if err := router.Reduce(&testEvent{action: TextSubjects{"user", "1", "added"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := router.Reduce(&testEvent{action: TextSubjects{"org", "1", "added"}}); !errors.Is(err, ErrUnhandledEvent) {
+		t.Errorf("expected %v, got: %v", ErrUnhandledEvent, err)
+	}
+}
+
+func TestRouter_Reduce_handlerError(t *testing.T) {
+	expectedErr := errors.New("handler failed")
+	var calledSecond bool
+	router := NewRouter().
+		Handle([]Subject{MultiToken}, func(Event) error { return expectedErr }).
+		Handle([]Subject{MultiToken}, func(Event) error {
+			calledSecond = true
+			return nil
+		})
+
+	if err := router.Reduce(&testEvent{action: TextSubjects{"user", "1", "added"}}); !errors.Is(err, expectedErr) {
+		t.Errorf("expected %v, got: %v", expectedErr, err)
+	}
+	if calledSecond {
+		t.Error("handlers after a failing handler must not be called")
+	}
+}
+
+func TestRouter_ReduceContext(t *testing.T) {
+	type key struct{}
+	var got []context.Context
+	router := NewRouter().
+		HandleContext([]Subject{TextSubject("user"), MultiToken}, func(ctx context.Context, _ Event) error {
+			got = append(got, ctx)
+			return nil
+		})
+
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	err := router.ReduceContext(ctx,
+		&testCause{Event: &testEvent{action: TextSubjects{"user", "1", "added"}}, id: "1", metadata: &Metadata{CorrelationID: "correlation"}},
+		&testCause{Event: &testEvent{action: TextSubjects{"user", "1", "removed"}}, id: "2"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 calls, got: %d", len(got))
+	}
+
+	tests := []struct {
+		causation   string
+		correlation string
+	}{
+		{causation: "1", correlation: "correlation"},
+		{causation: "2", correlation: ""},
+	}
+	for i, tt := range tests {
+		if causation := CausationFromContext(got[i]); causation != tt.causation {
+			t.Errorf("unexpected causation of event %d want: %q, got: %q", i, tt.causation, causation)
+		}
+		if correlation := CorrelationFromContext(got[i]); correlation != tt.correlation {
+			t.Errorf("unexpected correlation of event %d want: %q, got: %q", i, tt.correlation, correlation)
+		}
+		if got[i].Value(key{}) != "value" {
+			t.Errorf("context of event %d must be derived from the context of the filter", i)
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	var got string
+	router := NewRouter().
+		HandleContext([]Subject{MultiToken}, func(ctx context.Context, _ Event) error {
+			got = CausationFromContext(ctx)
+			return nil
+		})
+	event := &testCause{Event: &testEvent{action: TextSubjects{"user", "1", "added"}}, id: "event"}
+
+	tests := []struct {
+		name    string
+		reducer Reducer
+	}{
+		{
+			name:    "router",
+			reducer: router,
+		},
+		{
+			name:    "batching",
+			reducer: withBatching(&testBatchReducer{size: 1}, router),
+		},
+		{
+			name:    "redacting",
+			reducer: &redactingReducer{reducer: router},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			if err := Reduce(context.Background(), tt.reducer, event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != "event" {
+				t.Errorf("event not recorded as cause: %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package eventstore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidSignature is returned if the signature of an event doesn't match its content
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrUnsigned is returned if an event without signature is verified
+	ErrUnsigned = errors.New("event is not signed")
+	// ErrUnknownKey is returned if the verifier doesn't know the key id of a signature
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// Signer signs the content of commands during push
+type Signer interface {
+	// KeyID identifies the key used to sign, it's stored alongside the signature
+	KeyID() string
+	// Sign returns the signature of message
+	Sign(message []byte) ([]byte, error)
+}
+
+// Verifier checks signatures created by a [Signer]
+type Verifier interface {
+	// Verify returns [ErrInvalidSignature] if signature doesn't match message
+	// or [ErrUnknownKey] if the key is not known
+	Verify(keyID string, message, signature []byte) error
+}
+
+// SignedEvent can be implemented by an [Event]
+// if the eventstore stores signatures of the pushed commands
+type SignedEvent interface {
+	Event
+	// KeyID is the [Signer.KeyID] of the signature, empty if the event is not signed
+	KeyID() string
+	// VerifySignature checks the signature of the event using verifier.
+	// [ErrUnsigned] is returned if the event is not signed.
+	VerifySignature(verifier Verifier) error
+}
+
+var _ Signer = (*Ed25519Signer)(nil)
+
+// Ed25519Signer signs using ed25519
+type Ed25519Signer struct {
+	keyID string
+	key   ed25519.PrivateKey
+}
+
+// NewEd25519Signer signs using key, keyID is stored with each signature
+func NewEd25519Signer(keyID string, key ed25519.PrivateKey) *Ed25519Signer {
+	return &Ed25519Signer{keyID: keyID, key: key}
+}
+
+// KeyID implements [Signer]
+func (s *Ed25519Signer) KeyID() string {
+	return s.keyID
+}
+
+// Sign implements [Signer]
+func (s *Ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, message), nil
+}
+
+var _ Verifier = (Ed25519Verifier)(nil)
+
+// Ed25519Verifier verifies ed25519 signatures using the public keys mapped by their key id
+type Ed25519Verifier map[string]ed25519.PublicKey
+
+// Verify implements [Verifier]
+func (v Ed25519Verifier) Verify(keyID string, message, signature []byte) error {
+	key, ok := v[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	if !ed25519.Verify(key, message, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SigningMessage returns the canonical form of a command which is signed by a [Signer].
+// codec is the [Codec.Name] of the encoded payload and metadata is the json representation.
+// Json is canonicalized using [CanonicalJSON] so that formatting changes don't invalidate the signature.
+// Each field is prefixed with its length to prevent ambiguous concatenations.
+func SigningMessage(aggregate, action TextSubjects, revision uint16, codec string, payload, metadata []byte) (_ []byte, err error) {
+	if codec == JSONCodec.Name() {
+		if payload, err = CanonicalJSON(payload); err != nil {
+			return nil, err
+		}
+	}
+	if metadata, err = CanonicalJSON(metadata); err != nil {
+		return nil, err
+	}
+
+	var message []byte
+	writeField := func(field []byte) {
+		message = binary.BigEndian.AppendUint32(message, uint32(len(field)))
+		message = append(message, field...)
+	}
+	for _, subjects := range []TextSubjects{aggregate, action} {
+		writeField(binary.BigEndian.AppendUint32(nil, uint32(len(subjects))))
+		for _, subject := range subjects {
+			writeField([]byte(subject))
+		}
+	}
+	writeField(binary.BigEndian.AppendUint16(nil, revision))
+	writeField([]byte(codec))
+	writeField(payload)
+	writeField(metadata)
+
+	return message, nil
+}
+
+// CanonicalJSON returns data with sorted object keys and without insignificant whitespace.
+// Numbers are normalized without loss of precision, e.g. 1E-7 and 0.0000001 are written as 1e-7,
+// because stores like JSONB don't keep them as written. Empty data is returned as is.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	object, err := canonicalNumbers(object)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(object)
+}
+
+// canonicalNumbers replaces the numbers of object by their canonical form
+func canonicalNumbers(object any) (_ any, err error) {
+	switch value := object.(type) {
+	case json.Number:
+		return canonicalNumber(value)
+	case map[string]any:
+		for key, field := range value {
+			if value[key], err = canonicalNumbers(field); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, elem := range value {
+			if value[i], err = canonicalNumbers(elem); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return object, nil
+}
+
+// canonicalNumber returns the shortest exact form of number,
+// the significant digits without leading and trailing zeros followed by the exponent if it's not 0.
+// e.g. 1e-7, 1E-7 and 0.0000001 are returned as 1e-7, 42.0 and 4.2e1 as 42, 1.5 as 15e-1.
+func canonicalNumber(number json.Number) (json.Number, error) {
+	text := string(number)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+
+	mantissa, exponentText, hasExponent := strings.Cut(strings.ToLower(text), "e")
+	var exponent int64
+	if hasExponent {
+		var err error
+		if exponent, err = strconv.ParseInt(exponentText, 10, 64); err != nil {
+			return "", fmt.Errorf("invalid number %q: %w", number, err)
+		}
+	}
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+	digits := integer + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("invalid number %q", number)
+	}
+	exponent -= int64(len(fraction))
+
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0", nil
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exponent += int64(len(digits) - len(trimmed))
+
+	if exponent == 0 {
+		return json.Number(sign + trimmed), nil
+	}
+	return json.Number(sign + trimmed + "e" + strconv.FormatInt(exponent, 10)), nil
+}
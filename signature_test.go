@@ -0,0 +1,188 @@
+package eventstore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSigningMessage(t *testing.T) {
+	want, err := SigningMessage(TextSubjects{"user", "1"}, TextSubjects{"user", "1", "added"}, 1, JSONCodec.Name(), []byte(`{"name":"gigi","age":42}`), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		aggregate TextSubjects
+		action    TextSubjects
+		revision  uint16
+		codec     string
+		payload   []byte
+		metadata  []byte
+		isEqual   bool
+	}{
+		{
+			name:      "formatted payload",
+			aggregate: TextSubjects{"user", "1"},
+			action:    TextSubjects{"user", "1", "added"},
+			revision:  1,
+			codec:     JSONCodec.Name(),
+			payload:   []byte(`{ "age": 42, "name": "gigi" }`),
+			isEqual:   true,
+		},
+		{
+			name:      "changed payload",
+			aggregate: TextSubjects{"user", "1"},
+			action:    TextSubjects{"user", "1", "added"},
+			revision:  1,
+			codec:     JSONCodec.Name(),
+			payload:   []byte(`{"name":"gugu","age":42}`),
+		},
+		{
+			name:      "moved subject",
+			aggregate: TextSubjects{"user"},
+			action:    TextSubjects{"1", "user", "1", "added"},
+			revision:  1,
+			codec:     JSONCodec.Name(),
+			payload:   []byte(`{"name":"gigi","age":42}`),
+		},
+		{
+			name:      "revision",
+			aggregate: TextSubjects{"user", "1"},
+			action:    TextSubjects{"user", "1", "added"},
+			revision:  2,
+			codec:     JSONCodec.Name(),
+			payload:   []byte(`{"name":"gigi","age":42}`),
+		},
+		{
+			name:      "metadata",
+			aggregate: TextSubjects{"user", "1"},
+			action:    TextSubjects{"user", "1", "added"},
+			revision:  1,
+			codec:     JSONCodec.Name(),
+			payload:   []byte(`{"name":"gigi","age":42}`),
+			metadata:  []byte(`{"creator":"gigi"}`),
+		},
+		{
+			name:      "codec",
+			aggregate: TextSubjects{"user", "1"},
+			action:    TextSubjects{"user", "1", "added"},
+			revision:  1,
+			codec:     "other",
+			payload:   []byte(`{"name":"gigi","age":42}`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SigningMessage(tt.aggregate, tt.action, tt.revision, tt.codec, tt.payload, tt.metadata)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bytes.Equal(got, want) != tt.isEqual {
+				t.Errorf("expected equal messages to be %v", tt.isEqual)
+			}
+		})
+	}
+}
+
+func TestEd25519(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	signer := NewEd25519Signer("key-1", private)
+	message := []byte("message")
+	signature, err := signer.Sign(message)
+	if err != nil {
+		t.Fatalf("unable to sign: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier Ed25519Verifier
+		keyID    string
+		message  []byte
+		want     error
+	}{
+		{
+			name:     "valid",
+			verifier: Ed25519Verifier{"key-1": public},
+			keyID:    signer.KeyID(),
+			message:  message,
+		},
+		{
+			name:     "tampered",
+			verifier: Ed25519Verifier{"key-1": public},
+			keyID:    signer.KeyID(),
+			message:  []byte("tampered"),
+			want:     ErrInvalidSignature,
+		},
+		{
+			name:     "unknown key",
+			verifier: Ed25519Verifier{"key-2": public},
+			keyID:    signer.KeyID(),
+			message:  message,
+			want:     ErrUnknownKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.verifier.Verify(tt.keyID, tt.message, signature); !errors.Is(err, tt.want) {
+				t.Errorf("unexpected error want: %v, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "sorted keys",
+			data: `{ "name": "gigi", "age": 42 }`,
+			want: `{"age":42,"name":"gigi"}`,
+		},
+		{
+			name: "exponent",
+			data: `{"small":1E-7,"big":1e+21}`,
+			want: `{"big":1e21,"small":1e-7}`,
+		},
+		{
+			name: "decimal",
+			data: `[0.0000001,1.50,42.0,4.2e1,-0.5,0.0,100]`,
+			want: `[1e-7,15e-1,42,42,-5e-1,0,1e2]`,
+		},
+		{
+			name: "nested",
+			data: `{"values":[{"amount":1.0}]}`,
+			want: `{"values":[{"amount":1}]}`,
+		},
+		{
+			name: "precision",
+			data: `12345678901234567890.123456789`,
+			want: `12345678901234567890123456789e-9`,
+		},
+		{
+			name:    "invalid",
+			data:    `{"name":}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CanonicalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
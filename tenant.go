@@ -0,0 +1,44 @@
+package eventstore
+
+import "context"
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx which scopes pushes and filters to the tenant.
+// Commands are stored for the tenant and [Filter]s only return events of the tenant
+// unless [Filter.CrossTenant] is set.
+// Without a tenant the default tenant "" is used.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by [WithTenant]
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// TenantAggregate can be implemented by an [Aggregate]
+// whose commands are stored for a specific tenant.
+// The sequences of aggregates are counted per tenant.
+type TenantAggregate interface {
+	// Tenant is the tenant the commands are stored for
+	// an empty tenant falls back to [TenantFromContext]
+	Tenant() string
+}
+
+// TenantOf returns the tenant of the aggregate,
+// the tenant of an [TenantAggregate] takes precedence over the one of ctx
+func TenantOf(ctx context.Context, aggregate Aggregate) string {
+	if withTenant, ok := aggregate.(TenantAggregate); ok && withTenant.Tenant() != "" {
+		return withTenant.Tenant()
+	}
+	return TenantFromContext(ctx)
+}
+
+// TenantEvent can be implemented by an [Event]
+// to return the tenant it was stored for, e.g. for [Filter.CrossTenant] filters
+type TenantEvent interface {
+	// Tenant is the tenant of the event
+	Tenant() string
+}
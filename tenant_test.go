@@ -0,0 +1,56 @@
+package eventstore
+
+import (
+	"context"
+	"testing"
+)
+
+type testTenantAggregate struct {
+	Aggregate
+	tenant string
+}
+
+func (a *testTenantAggregate) Tenant() string {
+	return a.tenant
+}
+
+func TestTenantOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		aggregate Aggregate
+		want      string
+	}{
+		{
+			name:      "default",
+			ctx:       context.Background(),
+			aggregate: &testTenantAggregate{},
+			want:      "",
+		},
+		{
+			name:      "context",
+			ctx:       WithTenant(context.Background(), "context"),
+			aggregate: &testTenantAggregate{},
+			want:      "context",
+		},
+		{
+			name:      "aggregate",
+			ctx:       context.Background(),
+			aggregate: &testTenantAggregate{tenant: "aggregate"},
+			want:      "aggregate",
+		},
+		{
+			name:      "aggregate takes precedence",
+			ctx:       WithTenant(context.Background(), "context"),
+			aggregate: &testTenantAggregate{tenant: "aggregate"},
+			want:      "aggregate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TenantOf(tt.ctx, tt.aggregate); got != tt.want {
+				t.Errorf("TenantOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}